@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid session token")
+	ErrTokenExpired  = errors.New("session token expired")
+	ErrTokenMismatch = errors.New("session token does not match table or player")
+)
+
+const (
+	keySize = 32
+)
+
+type Claims struct {
+	TableId   string `json:"tid"`
+	PlayerId  string `json:"pid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Signer issues and verifies player session tokens.
+// Token has the form base64url(claims).base64url(HMAC-SHA256(claims)).
+type Signer struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewSigner(key []byte, ttl time.Duration) *Signer {
+	return &Signer{
+		key: key,
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// NewRandomKey generates a key suitable for NewSigner.
+// Tokens signed with it become invalid once the process exits.
+func NewRandomKey() []byte {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func (s *Signer) Issue(tableId string, playerId string) (string, error) {
+	claims := Claims{
+		TableId:   tableId,
+		PlayerId:  playerId,
+		ExpiresAt: s.now().Add(s.ttl).Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	return encodedPayload + "." + s.sign(encodedPayload), nil
+}
+
+func (s *Signer) Verify(token string) (Claims, error) {
+	encodedPayload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(encodedPayload))) {
+		return Claims{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+// Authorize checks that token is valid and was issued for the given table and player.
+func (s *Signer) Authorize(token string, tableId string, playerId string) error {
+	claims, err := s.Verify(token)
+	if err != nil {
+		return err
+	}
+	if claims.TableId != tableId || claims.PlayerId != playerId {
+		return ErrTokenMismatch
+	}
+	return nil
+}
+
+func (s *Signer) sign(encodedPayload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(encodedPayload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
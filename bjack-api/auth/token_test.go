@@ -0,0 +1,107 @@
+package auth_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/auth"
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	// Arrange
+	signer := auth.NewSigner([]byte("secret"), time.Hour)
+
+	// Act
+	token, err := signer.Issue("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := signer.Verify(token)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.TableId != "1" || claims.PlayerId != "2" {
+		t.Errorf("Expected claims for table 1 and player 2; got %+v", claims)
+	}
+}
+
+func TestVerifyTamperedToken(t *testing.T) {
+	// Arrange
+	signer := auth.NewSigner([]byte("secret"), time.Hour)
+	token, err := signer.Issue("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := signer.Issue("1", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, _ := strings.Cut(otherToken, ".")
+	_, signature, _ := strings.Cut(token, ".")
+
+	// Act
+	_, err = signer.Verify(payload + "." + signature)
+
+	// Assert
+	if !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken; got %v", err)
+	}
+}
+
+func TestVerifyTokenSignedWithOtherKey(t *testing.T) {
+	// Arrange
+	token, err := auth.NewSigner([]byte("other"), time.Hour).Issue("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err = auth.NewSigner([]byte("secret"), time.Hour).Verify(token)
+
+	// Assert
+	if !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken; got %v", err)
+	}
+}
+
+func TestVerifyExpiredToken(t *testing.T) {
+	// Arrange
+	signer := auth.NewSigner([]byte("secret"), -time.Minute)
+	token, err := signer.Issue("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err = signer.Verify(token)
+
+	// Assert
+	if !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired; got %v", err)
+	}
+}
+
+func TestAuthorizeMismatch(t *testing.T) {
+	// Arrange
+	signer := auth.NewSigner([]byte("secret"), time.Hour)
+	token, err := signer.Issue("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	tableErr := signer.Authorize(token, "9", "2")
+	playerErr := signer.Authorize(token, "1", "9")
+
+	// Assert
+	if !errors.Is(tableErr, auth.ErrTokenMismatch) {
+		t.Errorf("Expected ErrTokenMismatch for other table; got %v", tableErr)
+	}
+	if !errors.Is(playerErr, auth.ErrTokenMismatch) {
+		t.Errorf("Expected ErrTokenMismatch for other player; got %v", playerErr)
+	}
+}
@@ -0,0 +1,79 @@
+package blackjack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code is a stable, machine-readable identifier of an error.
+// Codes are part of the API, clients can rely on them not changing.
+type Code string
+
+const (
+	CodeInvalidArgument      Code = "INVALID_ARGUMENT"
+	CodeUnauthenticated      Code = "UNAUTHENTICATED"
+	CodePermissionDenied     Code = "PERMISSION_DENIED"
+	CodeTableNotFound        Code = "TABLE_NOT_FOUND"
+	CodeTableUnavailable     Code = "TABLE_UNAVAILABLE"
+	CodePlayerNotFound       Code = "PLAYER_NOT_FOUND"
+	CodeNameTaken            Code = "NAME_TAKEN"
+	CodeGameIsFull           Code = "GAME_IS_FULL"
+	CodeGameAlreadyStarted   Code = "GAME_ALREADY_STARTED"
+	CodeCardsAlreadyDealt    Code = "CARDS_ALREADY_DEALT"
+	CodeGameNotInProgress    Code = "GAME_NOT_IN_PROGRESS"
+	CodeOtherPlayerTurn      Code = "OTHER_PLAYER_TURN"
+	CodeInvalidBet           Code = "INVALID_BET"
+	CodeVersionMismatch      Code = "VERSION_MISMATCH"
+	CodeIdempotencyKeyReused Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeInternal             Code = "INTERNAL"
+)
+
+// Codes is the catalog of all error codes. The REST and gRPC servers map each of them
+// to a status of their protocol.
+var Codes = []Code{
+	CodeInvalidArgument,
+	CodeUnauthenticated,
+	CodePermissionDenied,
+	CodeTableNotFound,
+	CodeTableUnavailable,
+	CodePlayerNotFound,
+	CodeNameTaken,
+	CodeGameIsFull,
+	CodeGameAlreadyStarted,
+	CodeCardsAlreadyDealt,
+	CodeGameNotInProgress,
+	CodeOtherPlayerTurn,
+	CodeInvalidBet,
+	CodeVersionMismatch,
+	CodeIdempotencyKeyReused,
+	CodeInternal,
+}
+
+// Error is an error with a code from the catalog.
+// Errors with the same code match each other with errors.Is.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func NewError(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// CodeOf returns the code of the first Error in err's tree, or CodeInternal if there is none.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
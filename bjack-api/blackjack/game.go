@@ -1,22 +1,22 @@
 package blackjack
 
 import (
-	"crypto/rand"
-	"errors"
-	"math/big"
-	"strconv"
+	"fmt"
 
 	"github.com/GRO4T/bjack-api/constant"
 	"github.com/GRO4T/bjack-api/deck"
+	"github.com/GRO4T/bjack-api/ids"
 )
 
 var (
-	ErrNotFound           = errors.New("not found")
-	ErrGameIsFull         = errors.New("game is full")
-	ErrGameAlreadyStarted = errors.New("game already started")
-	ErrCardsAlreadyDealt  = errors.New("cards already dealt")
-	ErrGameNotInProgress  = errors.New("game not in progress")
-	ErrOtherPlayerTurn    = errors.New("other player's turn")
+	ErrNotFound           = NewError(CodePlayerNotFound, "player not found")
+	ErrGameIsFull         = NewError(CodeGameIsFull, "game is full")
+	ErrGameAlreadyStarted = NewError(CodeGameAlreadyStarted, "game already started")
+	ErrCardsAlreadyDealt  = NewError(CodeCardsAlreadyDealt, "cards already dealt")
+	ErrGameNotInProgress  = NewError(CodeGameNotInProgress, "game not in progress")
+	ErrOtherPlayerTurn    = NewError(CodeOtherPlayerTurn, "other player's turn")
+	ErrInvalidBet         = NewError(CodeInvalidBet, "invalid bet")
+	ErrNameTaken          = NewError(CodeNameTaken, "name taken")
 )
 
 const (
@@ -47,6 +47,18 @@ const (
 	Stand
 )
 
+// Rules are the settings of a table that stay the same for the whole game.
+type Rules struct {
+	MaxPlayers   int `json:"maxPlayers"`
+	InitialChips int `json:"initialChips"`
+}
+
+// DefaultRules are the rules of tables created without rules of their own.
+var DefaultRules = Rules{
+	MaxPlayers:   constant.MaxPlayers,
+	InitialChips: initialChips,
+}
+
 type Player struct {
 	Id      string  `json:"-"`
 	Name    string  `json:"name"`
@@ -62,6 +74,7 @@ type Blackjack struct {
 	Players        []*Player     `json:"players"`
 	State          State         `json:"state"`
 	CurrentPlayer  int           `json:"currentPlayer"`
+	Rules          Rules         `json:"-"`
 	onStateChanged func()        `json:"-"`
 }
 
@@ -84,23 +97,48 @@ func New(onStateChanged func()) Blackjack {
 		Players:        []*Player{},
 		State:          WaitingForPlayers,
 		CurrentPlayer:  1,
+		Rules:          DefaultRules,
 		onStateChanged: onStateChanged,
 	}
 }
 
+// Clone returns a deep copy of the game, e.g. to read it outside of the goroutine that owns it.
+func (b *Blackjack) Clone() *Blackjack {
+	clone := *b
+	clone.onStateChanged = nil
+	clone.Deck = append([]deck.Card{}, b.Deck...)
+	clone.Hands = make([][]deck.Card, len(b.Hands))
+	for i, hand := range b.Hands {
+		clone.Hands[i] = append([]deck.Card{}, hand...)
+	}
+	clone.Players = make([]*Player, len(b.Players))
+	for i, player := range b.Players {
+		p := *player
+		clone.Players[i] = &p
+	}
+	return &clone
+}
+
 func (b *Blackjack) AddPlayer(name string) (*Player, error) {
 	if b.State != WaitingForPlayers {
 		return nil, ErrGameAlreadyStarted
 	}
-	if len(b.Players) >= constant.MaxPlayers {
+	if len(b.Players) >= b.Rules.MaxPlayers {
 		return nil, ErrGameIsFull
 	}
 	for _, player := range b.Players {
 		if player.Name == name {
-			return nil, errors.New("Player with name " + name + " already exists")
+			return nil, NewError(CodeNameTaken, "player with name %v already exists", name)
 		}
 	}
-	newPlayer := NewPlayer(getRandomId(), name)
+	playerId, err := ids.NewPlayerId(func(id string) bool {
+		return b.findPlayer(id) != nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate player id: %w", err)
+	}
+	newPlayer := NewPlayer(playerId, name)
+	newPlayer.Chips = b.Rules.InitialChips
 	b.Players = append(b.Players, &newPlayer)
 	b.Hands = append(b.Hands, []deck.Card{})
 	if b.onStateChanged != nil {
@@ -163,6 +201,42 @@ func (b *Blackjack) TogglePlayerReady(playerId string) (*Player, error) {
 	return targetPlayer, nil
 }
 
+// SetPlayerReady makes the player ready or not ready. Nothing changes if the player already is.
+func (b *Blackjack) SetPlayerReady(playerId string, ready bool) (*Player, error) {
+	if b.State != WaitingForPlayers {
+		return nil, ErrGameAlreadyStarted
+	}
+	player := b.findPlayer(playerId)
+	if player == nil {
+		return nil, ErrNotFound
+	}
+	if player.IsReady == ready {
+		return player, nil
+	}
+	return b.TogglePlayerReady(playerId)
+}
+
+func (b *Blackjack) PlaceBet(playerId string, amount int) (*Player, error) {
+	if b.State != WaitingForPlayers {
+		return nil, ErrGameAlreadyStarted
+	}
+
+	player := b.findPlayer(playerId)
+	if player == nil {
+		return nil, ErrNotFound
+	}
+	if amount <= 0 || amount > player.Chips {
+		return nil, NewError(CodeInvalidBet, "bet must be between 1 and %d", player.Chips)
+	}
+	player.Bet = amount
+
+	if b.onStateChanged != nil {
+		b.onStateChanged()
+	}
+
+	return player, nil
+}
+
 func (b *Blackjack) Deal() error {
 	if b.State == CardsDealt {
 		return ErrCardsAlreadyDealt
@@ -236,7 +310,15 @@ func (b *Blackjack) DetermineOutcomes() {
 		return
 	}
 	for i := range b.GetPlayerCount() {
-		b.Players[i].Outcome = determineOutcome(b.GetDealerHand(), b.GetPlayerHand(i))
+		player := b.Players[i]
+		player.Outcome = determineOutcome(b.GetDealerHand(), b.GetPlayerHand(i))
+		switch player.Outcome {
+		case Win:
+			player.Chips += player.Bet
+		case Lose:
+			player.Chips -= player.Bet
+		case Undecided, Push:
+		}
 	}
 }
 
@@ -307,10 +389,11 @@ func isBlackjack(hand []deck.Card) bool {
 	return (isAce(0) && isTenOrQKJ(1)) || (isAce(1) && isTenOrQKJ(0))
 }
 
-func getRandomId() string {
-	id, err := rand.Int(rand.Reader, big.NewInt(constant.MaxId))
-	if err != nil {
-		panic(err)
+func (b *Blackjack) findPlayer(id string) *Player {
+	for _, player := range b.Players {
+		if player.Id == id {
+			return player
+		}
 	}
-	return strconv.Itoa(int(id.Int64()))
+	return nil
 }
@@ -0,0 +1,274 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/GRO4T/bjack-api/blackjack"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server. It is built in layers: the defaults,
+// then an optional JSON, YAML or TOML file, then environment variables and finally
+// command line flags, each overriding the ones before.
+type Config struct {
+	// Addr is where REST is served, together with gRPC unless GrpcAddr is set.
+	Addr        string          `json:"addr"`
+	GrpcAddr    string          `json:"grpcAddr"`
+	CorsOrigins []string        `json:"corsOrigins"`
+	Log         Log             `json:"log"`
+	Rules       blackjack.Rules `json:"rules"`
+	Timeouts    Timeouts        `json:"timeouts"`
+	DataDir     string          `json:"dataDir"`
+	// SessionKey signs session tokens. A random key is used if it is empty.
+	SessionKey string `json:"sessionKey"`
+
+	// PrintConfig asks to print the effective configuration and exit.
+	PrintConfig bool `json:"-"`
+	// Warnings about deprecated settings, to be logged once logging is set up.
+	Warnings []string `json:"-"`
+}
+
+type Log struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+type Timeouts struct {
+	Read            Duration `json:"read"`
+	Write           Duration `json:"write"`
+	Turn            Duration `json:"turn"`
+	TableTTL        Duration `json:"tableTtl"`
+	JanitorInterval Duration `json:"janitorInterval"`
+	SessionToken    Duration `json:"sessionToken"`
+	Idempotency     Duration `json:"idempotency"`
+	// Shutdown bounds how long rounds in progress are waited for on shutdown.
+	Shutdown Duration `json:"shutdown"`
+}
+
+// Duration is a time.Duration written as e.g. "90s" or "1h30m" in config files.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration: %w", err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+const redacted = "REDACTED"
+
+// nolint: mnd
+func Default() Config {
+	return Config{
+		Addr:  "0.0.0.0:8000",
+		Log:   Log{Level: "info", Format: "text"},
+		Rules: blackjack.DefaultRules,
+		Timeouts: Timeouts{
+			Read:            Duration(10 * time.Second),
+			Write:           Duration(10 * time.Second),
+			Turn:            Duration(time.Minute),
+			TableTTL:        Duration(30 * time.Minute),
+			JanitorInterval: Duration(time.Minute),
+			SessionToken:    Duration(24 * time.Hour),
+			Idempotency:     Duration(time.Hour),
+			Shutdown:        Duration(30 * time.Second),
+		},
+		DataDir: "data",
+	}
+}
+
+// Load builds the configuration from the command line arguments, without the program name,
+// and the environment. The file is named by the -config flag or the CONFIG_FILE variable.
+// The configuration is validated before it is returned.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	// The first pass only finds the file, flags are applied again once it is loaded.
+	scratch := Default()
+	fs := scratch.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err //nolint: wrapcheck
+	}
+	path := fs.Lookup("config").Value.String()
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+
+	cfg := Default()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.loadEnv(lookupEnv); err != nil {
+		return Config{}, err
+	}
+	fs = cfg.flagSet()
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err //nolint: wrapcheck
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("bjack-api", flag.ContinueOnError)
+	fs.String("config", "", "path of a JSON, YAML or TOML config file (env CONFIG_FILE)")
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	// Before gRPC was served alongside REST, -grpc switched the server from REST to gRPC.
+	fs.BoolFunc("grpc", "deprecated, gRPC is always served, see -grpc-addr", func(string) error {
+		c.Warnings = append(c.Warnings, "The -grpc flag is deprecated and has no effect, "+
+			"gRPC is served together with REST unless -grpc-addr is set")
+		return nil
+	})
+	for _, s := range c.settings() {
+		if s.flag != "" {
+			fs.Var(s.value, s.flag, fmt.Sprintf("%s (env %s)", s.usage, strings.Join(s.env, ", ")))
+		}
+	}
+	return fs
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		// YAML and TOML are converted to JSON, so that all formats follow the same field names and checks.
+		var tree any
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+		if data, err = json.Marshal(tree); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+	case ".toml":
+		var tree map[string]any
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+		if data, err = json.Marshal(tree); err != nil {
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file format %q", ext)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv(lookupEnv func(string) (string, bool)) error {
+	for _, s := range c.settings() {
+		for _, name := range s.env {
+			value, ok := lookupEnv(name)
+			if !ok || value == "" {
+				continue
+			}
+			if err := s.value.Set(value); err != nil {
+				return fmt.Errorf("invalid %v: %w", name, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	_, _, err := net.SplitHostPort(c.Addr)
+	check(err == nil, "addr %q is not a host:port address", c.Addr)
+	if c.GrpcAddr != "" {
+		_, _, err := net.SplitHostPort(c.GrpcAddr)
+		check(err == nil, "grpcAddr %q is not a host:port address", c.GrpcAddr)
+		check(c.GrpcAddr != c.Addr, "grpcAddr must differ from addr, leave it empty to share the port")
+	}
+	check(len(c.CorsOrigins) > 0, "corsOrigins must name the origin of the UI")
+	for _, origin := range c.CorsOrigins {
+		u, err := url.Parse(origin)
+		check(origin == "*" || err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"corsOrigins: %q is not an http(s) origin", origin)
+	}
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level %q is not one of debug, info, warn, error", c.Log.Level)
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format %q is not one of text, json", c.Log.Format)
+	check(c.Rules.MaxPlayers > 0, "rules.maxPlayers must be positive")
+	check(c.Rules.InitialChips > 0, "rules.initialChips must be positive")
+	for _, timeout := range []struct {
+		name  string
+		value Duration
+	}{
+		{"read", c.Timeouts.Read},
+		{"write", c.Timeouts.Write},
+		{"turn", c.Timeouts.Turn},
+		{"tableTtl", c.Timeouts.TableTTL},
+		{"janitorInterval", c.Timeouts.JanitorInterval},
+		{"sessionToken", c.Timeouts.SessionToken},
+		{"idempotency", c.Timeouts.Idempotency},
+		{"shutdown", c.Timeouts.Shutdown},
+	} {
+		check(timeout.value > 0, "timeouts.%v must be positive", timeout.name)
+	}
+	check(c.DataDir != "", "dataDir must not be empty")
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Print writes the configuration as JSON, which can be used as a config file.
+// The session key is redacted.
+func (c Config) Print(w io.Writer) error {
+	if c.SessionKey != "" {
+		c.SessionKey = redacted
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("failed to print config: %w", err)
+	}
+	return nil
+}
+
+// Logger returns a logger writing to w in the configured format, from the configured level.
+// The configuration must be valid.
+func (l Log) Logger(w io.Writer) *slog.Logger {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(l.Level))
+	options := &slog.HandlerOptions{Level: level}
+	if l.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
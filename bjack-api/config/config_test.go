@@ -0,0 +1,199 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/config"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	// Act
+	cfg, err := config.Load(nil, env(map[string]string{"UI_URL": "http://localhost:5173"}))
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != config.Default().Addr {
+		t.Errorf("Expected addr %v; got %v", config.Default().Addr, cfg.Addr)
+	}
+	if len(cfg.CorsOrigins) != 1 || cfg.CorsOrigins[0] != "http://localhost:5173" {
+		t.Errorf("Expected UI_URL to be the CORS origin; got %v", cfg.CorsOrigins)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	// Arrange
+	path := writeFile(t, "config.yaml", `
+addr: 127.0.0.1:9000
+corsOrigins: [http://a.example, http://b.example]
+log:
+  level: warn
+rules:
+  maxPlayers: 5
+timeouts:
+  turn: 30s
+`)
+	vars := env(map[string]string{
+		"LOG_LEVEL":   "error",
+		"MAX_PLAYERS": "6",
+	})
+
+	// Act
+	cfg, err := config.Load([]string{"-config", path, "-max-players", "7"}, vars)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != "127.0.0.1:9000" {
+		t.Errorf("Expected the address from the file; got %v", cfg.Addr)
+	}
+	if len(cfg.CorsOrigins) != 2 {
+		t.Errorf("Expected 2 CORS origins; got %v", cfg.CorsOrigins)
+	}
+	if time.Duration(cfg.Timeouts.Turn) != 30*time.Second {
+		t.Errorf("Expected the turn timeout from the file; got %v", cfg.Timeouts.Turn)
+	}
+	if cfg.Log.Level != "error" {
+		t.Errorf("Expected the environment to override the file; got %v", cfg.Log.Level)
+	}
+	if cfg.Rules.MaxPlayers != 7 {
+		t.Errorf("Expected the flag to override the environment; got %v", cfg.Rules.MaxPlayers)
+	}
+	if cfg.Rules.InitialChips != config.Default().Rules.InitialChips {
+		t.Errorf("Expected the default initial chips; got %v", cfg.Rules.InitialChips)
+	}
+}
+
+func TestLoadJsonFileFromEnv(t *testing.T) {
+	// Arrange
+	path := writeFile(t, "config.json", `{"corsOrigins": ["*"], "dataDir": "/var/lib/bjack"}`)
+
+	// Act
+	cfg, err := config.Load(nil, env(map[string]string{"CONFIG_FILE": path}))
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DataDir != "/var/lib/bjack" {
+		t.Errorf("Expected the data directory from the file; got %v", cfg.DataDir)
+	}
+}
+
+func TestLoadTomlFile(t *testing.T) {
+	// Arrange
+	path := writeFile(t, "config.toml", `
+corsOrigins = ["http://a.example"]
+
+[rules]
+maxPlayers = 5
+
+[timeouts]
+turn = "30s"
+`)
+
+	// Act
+	cfg, err := config.Load([]string{"-config", path}, env(nil))
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Rules.MaxPlayers != 5 {
+		t.Errorf("Expected the max players from the file; got %v", cfg.Rules.MaxPlayers)
+	}
+	if time.Duration(cfg.Timeouts.Turn) != 30*time.Second {
+		t.Errorf("Expected the turn timeout from the file; got %v", cfg.Timeouts.Turn)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	// Arrange
+	path := writeFile(t, "config.yaml", "corsOrigins: ['*']\nlisten: 127.0.0.1:9000\n")
+
+	// Act
+	_, err := config.Load([]string{"-config", path}, env(nil))
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "listen") {
+		t.Errorf("Expected an error about the unknown field; got %v", err)
+	}
+}
+
+func TestLoadWarnsAboutDeprecatedGrpcFlag(t *testing.T) {
+	// Act
+	cfg, err := config.Load([]string{"-grpc"}, env(map[string]string{"UI_URL": "http://localhost:5173"}))
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Warnings) != 1 || !strings.Contains(cfg.Warnings[0], "-grpc") {
+		t.Errorf("Expected a warning about -grpc; got %v", cfg.Warnings)
+	}
+}
+
+func TestValidateReportsEverySetting(t *testing.T) {
+	// Arrange
+	args := []string{"-cors-origins", "localhost", "-log-format", "xml", "-turn-timeout", "0s"}
+
+	// Act
+	_, err := config.Load(args, env(nil))
+
+	// Assert
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, setting := range []string{"corsOrigins", "log.format", "timeouts.turn"} {
+		if !strings.Contains(err.Error(), setting) {
+			t.Errorf("Expected an error about %v; got %v", setting, err)
+		}
+	}
+}
+
+func TestPrintRedactsSessionKey(t *testing.T) {
+	// Arrange
+	cfg, err := config.Load(nil, env(map[string]string{"CORS_ORIGINS": "*", "SESSION_KEY": "secret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+
+	// Act
+	err = cfg.Print(&out)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "secret") {
+		t.Errorf("Expected the session key to be redacted; got %v", out.String())
+	}
+	path := writeFile(t, "printed.json", out.String())
+	if _, err := config.Load([]string{"-config", path}, env(nil)); err != nil {
+		t.Errorf("Expected the printed config to load; got %v", err)
+	}
+}
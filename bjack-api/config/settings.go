@@ -0,0 +1,101 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds a config field to its command line flag and environment variables.
+// The first variable that is set wins, later ones are kept for backward compatibility.
+type setting struct {
+	flag  string
+	env   []string
+	usage string
+	value flag.Value
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"addr", []string{"LISTEN_ADDR"}, "address to serve REST, and gRPC unless -grpc-addr is set", stringValue{&c.Addr}},
+		{"grpc-addr", []string{"GRPC_ADDR"}, "address to serve gRPC on a port of its own", stringValue{&c.GrpcAddr}},
+		{"cors-origins", []string{"CORS_ORIGINS", "UI_URL"}, "comma-separated origins allowed to call the REST API", listValue{&c.CorsOrigins}},
+		{"log-level", []string{"LOG_LEVEL"}, "debug, info, warn or error", stringValue{&c.Log.Level}},
+		{"log-format", []string{"LOG_FORMAT"}, "text or json", stringValue{&c.Log.Format}},
+		{"max-players", []string{"MAX_PLAYERS"}, "players allowed at a new table", intValue{&c.Rules.MaxPlayers}},
+		{"initial-chips", []string{"INITIAL_CHIPS"}, "chips of a player joining a new table", intValue{&c.Rules.InitialChips}},
+		{"read-timeout", []string{"READ_TIMEOUT"}, "time to read an HTTP request", &c.Timeouts.Read},
+		{"write-timeout", []string{"WRITE_TIMEOUT"}, "time to write an HTTP response", &c.Timeouts.Write},
+		{"turn-timeout", []string{"TURN_TIMEOUT"}, "time a player has to act before standing automatically", &c.Timeouts.Turn},
+		{"table-ttl", []string{"TABLE_TTL"}, "time after which idle tables are removed", &c.Timeouts.TableTTL},
+		{"janitor-interval", []string{"JANITOR_INTERVAL"}, "how often idle tables are looked for", &c.Timeouts.JanitorInterval},
+		{"session-token-ttl", []string{"SESSION_TOKEN_TTL"}, "validity of session tokens", &c.Timeouts.SessionToken},
+		{"idempotency-ttl", []string{"IDEMPOTENCY_TTL"}, "how long responses are kept for retried requests", &c.Timeouts.Idempotency},
+		{"shutdown-timeout", []string{"SHUTDOWN_TIMEOUT"}, "time to wait for rounds in progress on shutdown", &c.Timeouts.Shutdown},
+		{"data-dir", []string{"DATA_DIR"}, "directory where tables are persisted", stringValue{&c.DataDir}},
+		// Secrets do not get a flag, so that they do not show up in the process list.
+		{"", []string{"SESSION_KEY"}, "", stringValue{&c.SessionKey}},
+	}
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(s string) error {
+	return d.UnmarshalText([]byte(s))
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+type listValue struct{ p *[]string }
+
+func (v listValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strings.Join(*v.p, ",")
+}
+
+func (v listValue) Set(s string) error {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*v.p = list
+	return nil
+}
+
+type intValue struct{ p *int }
+
+func (v intValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strconv.Itoa(*v.p)
+}
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid number: %w", err)
+	}
+	*v.p = n
+	return nil
+}
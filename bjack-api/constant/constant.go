@@ -1,6 +1,9 @@
 package constant
 
+import "time"
+
 const (
-	MaxId      int64 = 1000
-	MaxPlayers int   = 3
+	MaxPlayers int = 3
+	// How long API handlers wait for a table to process a command.
+	CommandTimeout = 5 * time.Second
 )
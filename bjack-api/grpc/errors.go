@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/requestid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ErrorDomain is the domain of the ErrorInfo attached to every error status.
+// The reason of the ErrorInfo is the code of the error from the blackjack catalog.
+const ErrorDomain = "bjack-api"
+
+var grpcCodes = map[blackjack.Code]codes.Code{
+	blackjack.CodeInvalidArgument:      codes.InvalidArgument,
+	blackjack.CodeUnauthenticated:      codes.Unauthenticated,
+	blackjack.CodePermissionDenied:     codes.PermissionDenied,
+	blackjack.CodeTableNotFound:        codes.NotFound,
+	blackjack.CodeTableUnavailable:     codes.Unavailable,
+	blackjack.CodePlayerNotFound:       codes.NotFound,
+	blackjack.CodeNameTaken:            codes.AlreadyExists,
+	blackjack.CodeGameIsFull:           codes.FailedPrecondition,
+	blackjack.CodeGameAlreadyStarted:   codes.FailedPrecondition,
+	blackjack.CodeCardsAlreadyDealt:    codes.FailedPrecondition,
+	blackjack.CodeGameNotInProgress:    codes.FailedPrecondition,
+	blackjack.CodeOtherPlayerTurn:      codes.FailedPrecondition,
+	blackjack.CodeInvalidBet:           codes.InvalidArgument,
+	blackjack.CodeVersionMismatch:      codes.Aborted,
+	blackjack.CodeIdempotencyKeyReused: codes.FailedPrecondition,
+	blackjack.CodeInternal:             codes.Internal,
+}
+
+// newStatus returns an error status with the gRPC code matching code and an ErrorInfo detail.
+func newStatus(code blackjack.Code, message string, details ...protoadapt.MessageV1) error {
+	grpcCode, ok := grpcCodes[code]
+	if !ok {
+		grpcCode = codes.Internal
+	}
+	st := status.New(grpcCode, message)
+	details = append([]protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason: string(code),
+		Domain: ErrorDomain,
+	}}, details...)
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err() //nolint: wrapcheck
+	}
+	return withDetails.Err() //nolint: wrapcheck
+}
+
+// invalidArgument returns an InvalidArgument status that points at the offending request field.
+func invalidArgument(field string, description string) error {
+	return newStatus(blackjack.CodeInvalidArgument, description, &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	})
+}
+
+// errorStatus converts an error returned by the registry or a table command to a gRPC status.
+// Errors missing from the catalog are logged with the id of the request and reported
+// as internal errors, without details that are of no use to clients.
+func errorStatus(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error()) //nolint: wrapcheck
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error()) //nolint: wrapcheck
+	}
+	var e *blackjack.Error
+	if errors.As(err, &e) {
+		return newStatus(e.Code, e.Message)
+	}
+	slog.Error(err.Error(), "requestId", requestid.FromContext(ctx))
+	return newStatus(blackjack.CodeInternal, "Internal server error")
+}
@@ -0,0 +1,119 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func errorInfo(t *testing.T, st *status.Status) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatalf("Expected ErrorInfo in %v", st.Details())
+	return nil
+}
+
+func TestEveryErrorCodeHasGrpcCode(t *testing.T) {
+	for _, code := range blackjack.Codes {
+		// Act
+		_, ok := bgrpc.GrpcCode(code)
+
+		// Assert
+		if !ok {
+			t.Errorf("Expected a gRPC code for %v", code)
+		}
+	}
+}
+
+func TestGrpcApi_AddPlayerWithTakenName(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	if _, err := game.AddPlayer("Player 1"); err != nil {
+		t.Fatal(err)
+	}
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.AddPlayer(context.Background(), &pb.AddPlayerRequest{TableId: testTableId, PlayerName: "Player 1"})
+
+	// Assert
+	st := status.Convert(err)
+	if st.Code() != codes.AlreadyExists {
+		t.Fatalf("Expected AlreadyExists; got %v", err)
+	}
+	info := errorInfo(t, st)
+	if info.Reason != string(blackjack.CodeNameTaken) || info.Domain != bgrpc.ErrorDomain {
+		t.Errorf("Expected %v reason; got %v", blackjack.CodeNameTaken, info)
+	}
+}
+
+func TestGrpcApi_InvalidArgumentPointsAtField(t *testing.T) {
+	// Arrange
+	_, client := Setup(t)
+
+	// Act
+	_, err := client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: "../1"})
+
+	// Assert
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument; got %v", err)
+	}
+	if info := errorInfo(t, st); info.Reason != string(blackjack.CodeInvalidArgument) {
+		t.Errorf("Expected %v reason; got %v", blackjack.CodeInvalidArgument, info)
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			violations = badRequest.FieldViolations
+		}
+	}
+	if len(violations) != 1 || violations[0].Field != "tableId" {
+		t.Errorf("Expected a tableId violation; got %v", violations)
+	}
+}
+
+func TestGrpcApi_MissingTable(t *testing.T) {
+	// Arrange
+	_, client := Setup(t)
+
+	// Act
+	_, err := client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: testTableId})
+
+	// Assert
+	st := status.Convert(err)
+	if st.Code() != codes.NotFound {
+		t.Fatalf("Expected NotFound; got %v", err)
+	}
+	if info := errorInfo(t, st); info.Reason != string(blackjack.CodeTableNotFound) {
+		t.Errorf("Expected %v reason; got %v", blackjack.CodeTableNotFound, info)
+	}
+}
+
+func TestErrorStatusHidesInternalErrors(t *testing.T) {
+	// Arrange
+	err := errors.New("failed to open /var/lib/bjack/tables.json")
+
+	// Act
+	st := status.Convert(bgrpc.ErrorStatus(context.Background(), err))
+
+	// Assert
+	if st.Code() != codes.Internal || st.Message() != "Internal server error" {
+		t.Errorf("Expected a generic Internal status; got %v", st)
+	}
+	if info := errorInfo(t, st); info.Reason != string(blackjack.CodeInternal) {
+		t.Errorf("Expected %v reason; got %v", blackjack.CodeInternal, info)
+	}
+}
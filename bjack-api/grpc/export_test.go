@@ -0,0 +1,19 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"google.golang.org/grpc/codes"
+)
+
+// GrpcCode returns the gRPC code of statuses for errors with the code.
+func GrpcCode(code blackjack.Code) (codes.Code, bool) {
+	grpcCode, ok := grpcCodes[code]
+	return grpcCode, ok
+}
+
+// ErrorStatus converts err to the status returned to clients.
+func ErrorStatus(ctx context.Context, err error) error {
+	return errorStatus(ctx, err)
+}
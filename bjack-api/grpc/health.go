@@ -0,0 +1,31 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/GRO4T/bjack-api/proto"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// WatchReadiness sets the status of the Blackjack service, and of the server as a whole,
+// in h from ready every interval until ctx is done. Calling h.Shutdown makes h report
+// NOT_SERVING from then on, regardless of ready.
+func WatchReadiness(ctx context.Context, h *health.Server, ready func() error, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		if ready() != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		h.SetServingStatus("", status)
+		h.SetServingStatus(pb.Blackjack_ServiceDesc.ServiceName, status)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func waitForStatus(t *testing.T, h *health.Server, expected healthpb.HealthCheckResponse_ServingStatus) {
+	t.Helper()
+	request := &healthpb.HealthCheckRequest{Service: pb.Blackjack_ServiceDesc.ServiceName}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := h.Check(context.Background(), request)
+		if err == nil && resp.Status == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %v; got %v, %v", expected, resp, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchReadiness(t *testing.T) {
+	// Arrange
+	h := health.NewServer()
+	var unavailable atomic.Bool
+	ready := func() error {
+		if unavailable.Load() {
+			return errors.New("unavailable")
+		}
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go bgrpc.WatchReadiness(ctx, h, ready, 10*time.Millisecond)
+	waitForStatus(t, h, healthpb.HealthCheckResponse_SERVING)
+
+	// Act
+	unavailable.Store(true)
+
+	// Assert
+	waitForStatus(t, h, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func TestWatchReadinessAfterShutdown(t *testing.T) {
+	// Arrange
+	h := health.NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go bgrpc.WatchReadiness(ctx, h, func() error { return nil }, 10*time.Millisecond)
+	waitForStatus(t, h, healthpb.HealthCheckResponse_SERVING)
+
+	// Act
+	h.Shutdown()
+	time.Sleep(50 * time.Millisecond)
+
+	// Assert
+	waitForStatus(t, h, healthpb.HealthCheckResponse_NOT_SERVING)
+}
@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/GRO4T/bjack-api/idempotency"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// IdempotencyKeyMetadata is the metadata key of the idempotency key of a call.
+	IdempotencyKeyMetadata = "idempotency-key"
+	// ReplayedMetadata is the header metadata key marking replayed replies.
+	ReplayedMetadata = "idempotent-replayed"
+)
+
+// SavedReply is the outcome of a call kept for replaying to retried calls.
+type SavedReply struct {
+	Reply any
+	Err   error
+}
+
+var mutatingMethods = map[string]bool{
+	pb.Blackjack_CreateGame_FullMethodName:        true,
+	pb.Blackjack_AddPlayer_FullMethodName:         true,
+	pb.Blackjack_RemovePlayer_FullMethodName:      true,
+	pb.Blackjack_TogglePlayerReady_FullMethodName: true,
+	pb.Blackjack_SetPlayerReady_FullMethodName:    true,
+	pb.Blackjack_PlaceBet_FullMethodName:          true,
+	pb.Blackjack_PlayerAction_FullMethodName:      true,
+}
+
+// IdempotencyInterceptor makes mutating calls with idempotency-key metadata execute at most
+// once within the window of the cache. Retries get the reply of the first call, with
+// idempotent-replayed header metadata. Transient failures are not kept, so that a retry
+// can succeed. Keys are scoped to the authorization metadata. Calls without it share
+// a key space, so replies issuing session tokens are not kept either.
+func IdempotencyInterceptor(cache *idempotency.Cache[SavedReply]) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get(IdempotencyKeyMetadata)
+		if len(keys) == 0 || keys[0] == "" || !mutatingMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		key := keys[0]
+		if len(key) > idempotency.MaxKeyLength {
+			return nil, invalidArgument(IdempotencyKeyMetadata,
+				fmt.Sprintf("Idempotency key must not be longer than %d characters", idempotency.MaxKeyLength))
+		}
+		fingerprint, err := callFingerprint(info.FullMethod, req)
+		if err != nil {
+			return nil, errorStatus(ctx, err)
+		}
+
+		scope := strings.Join(md.Get("authorization"), ",") + "\x00" + key
+		saved, replayed, err := cache.Do(ctx, scope, fingerprint, func() (SavedReply, bool) {
+			reply, err := handler(ctx, req)
+			return SavedReply{Reply: reply, Err: err}, !isTransient(err) && !hasToken(reply)
+		})
+		if err != nil {
+			return nil, errorStatus(ctx, err)
+		}
+		if replayed {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(ReplayedMetadata, "true"))
+		}
+		return saved.Reply, saved.Err
+	}
+}
+
+// callFingerprint tells a retry, which calls the same method with the same request, from another call.
+func callFingerprint(method string, req any) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(method + "\n"))
+	if message, ok := req.(proto.Message); ok {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode request: %w", err)
+		}
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// tokenMessage is implemented by the replies of calls issuing session tokens.
+type tokenMessage interface {
+	GetToken() string
+}
+
+func hasToken(reply any) bool {
+	m, ok := reply.(tokenMessage)
+	return ok && m.GetToken() != ""
+}
+
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled, codes.Unknown, codes.DeadlineExceeded, codes.Internal, codes.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	"github.com/GRO4T/bjack-api/idempotency"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// nolint: ireturn
+func setupIdempotent(t *testing.T) (*bgrpc.BlackjackServer, pb.BlackjackClient) {
+	t.Helper()
+	cache := idempotency.NewCache[bgrpc.SavedReply](time.Hour)
+	return Setup(t, grpc.UnaryInterceptor(bgrpc.IdempotencyInterceptor(cache)))
+}
+
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, bgrpc.IdempotencyKeyMetadata, key)
+}
+
+func TestGrpcApi_RetriedCreateGame(t *testing.T) {
+	// Arrange
+	server, client := setupIdempotent(t)
+	ctx := withIdempotencyKey(context.Background(), "key-1")
+	first, err := client.CreateGame(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var header metadata.MD
+	second, err := client.CreateGame(ctx, &emptypb.Empty{}, grpc.Header(&header))
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.TableId != first.TableId {
+		t.Errorf("Expected table %v; got %v", first.TableId, second.TableId)
+	}
+	if values := header.Get(bgrpc.ReplayedMetadata); len(values) != 1 || values[0] != "true" {
+		t.Errorf("Expected the reply to be marked as replayed; got %v", header)
+	}
+	if server.Tables.Len() != 1 {
+		t.Errorf("Expected 1 table; got %v", server.Tables.Len())
+	}
+}
+
+func TestGrpcApi_RetriedPlayerAction(t *testing.T) {
+	// Arrange
+	server, client := setupIdempotent(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	server.Tables.Put(testTableId, &game)
+	ctx := withIdempotencyKey(authorizedContext(t, server, testTableId, newPlayer.Id), "key-1")
+	req := &pb.PlayerActionRequest{TableId: testTableId, PlayerId: newPlayer.Id, Action: pb.Action_HIT}
+	if _, err := client.PlayerAction(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err := client.PlayerAction(ctx, req)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(game.GetPlayerHand(0)) != 3 {
+		t.Errorf("Expected 3 cards; got %v", len(game.GetPlayerHand(0)))
+	}
+}
+
+func TestGrpcApi_IdempotencyKeyReusedForOtherRequest(t *testing.T) {
+	// Arrange
+	server, client := setupIdempotent(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+	ctx := withIdempotencyKey(context.Background(), "key-1")
+	if _, err := client.CreateGame(ctx, &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err := client.AddPlayer(ctx, &pb.AddPlayerRequest{TableId: testTableId, PlayerName: "Player 1"})
+
+	// Assert
+	st := status.Convert(err)
+	if st.Code() != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition; got %v", err)
+	}
+	if info := errorInfo(t, st); info.Reason != string(blackjack.CodeIdempotencyKeyReused) {
+		t.Errorf("Expected %v reason; got %v", blackjack.CodeIdempotencyKeyReused, info)
+	}
+}
+
+func TestGrpcApi_AddPlayerReplyNotKept(t *testing.T) {
+	// Arrange
+	server, client := setupIdempotent(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+	ctx := withIdempotencyKey(context.Background(), "key-1")
+	req := &pb.AddPlayerRequest{TableId: testTableId, PlayerName: "Player 1"}
+	if _, err := client.AddPlayer(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var header metadata.MD
+	_, err := client.AddPlayer(ctx, req, grpc.Header(&header))
+
+	// Assert
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists rather than the session token of the first call; got %v", err)
+	}
+	if values := header.Get(bgrpc.ReplayedMetadata); len(values) != 0 {
+		t.Errorf("Expected the reply not to be replayed; got %v", header)
+	}
+}
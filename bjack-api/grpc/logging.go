@@ -0,0 +1,139 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/GRO4T/bjack-api/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingInterceptors give every call an id, keeping the one in the x-request-id metadata
+// if it is valid, and send it back as header metadata. Once a call is done, it is logged
+// together with its status code, latency and the table and player it was about.
+// Server errors are logged as errors, unavailable tables as warnings.
+func LoggingInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, id := withRequestId(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.Metadata, id))
+		reply, err := handler(ctx, req)
+		logCall(ctx, id, info.FullMethod, start, err, req, reply)
+		return reply, err
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, id := withRequestId(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestid.Metadata, id))
+		wrapped := &loggedStream{ServerStream: ss, ctx: ctx}
+		err := handler(srv, wrapped)
+		logCall(ctx, id, info.FullMethod, start, err, wrapped.req)
+		return err
+	}
+	return unary, stream
+}
+
+// RecoveryInterceptors turn a panic in a handler into an Internal status and log it with its
+// stack trace, so that a bug hit by one call does not take down the server.
+func RecoveryInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	recovered := func(ctx context.Context, method string, err *error) {
+		if p := recover(); p != nil {
+			slog.Error(fmt.Sprintf("Panic serving %v: %v", method, p),
+				"requestId", requestid.FromContext(ctx), "stack", string(debug.Stack()))
+			*err = status.Error(codes.Internal, "Internal server error")
+		}
+	}
+	unary := func(
+		ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (reply any, err error) {
+		defer recovered(ctx, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recovered(ss.Context(), info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
+
+func withRequestId(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if ids := md.Get(requestid.Metadata); len(ids) > 0 {
+		id = ids[0]
+	}
+	id = requestid.Ensure(id)
+	return requestid.NewContext(ctx, id), id
+}
+
+// tableMessage is implemented by the requests and replies of calls about a table.
+type tableMessage interface {
+	GetTableId() string
+}
+
+// playerMessage is implemented by the requests and replies of calls about a player.
+type playerMessage interface {
+	GetPlayerId() string
+}
+
+// logCall logs a call. The table and player ids are taken from the first of messages having them.
+func logCall(ctx context.Context, id string, method string, start time.Time, err error, messages ...any) {
+	code := status.Code(err)
+	attrs := []any{
+		"requestId", id,
+		"method", method,
+		"code", code.String(),
+		"latency", time.Since(start),
+	}
+	var tableId, playerId string
+	for _, m := range messages {
+		if m, ok := m.(tableMessage); ok && tableId == "" {
+			tableId = m.GetTableId()
+		}
+		if m, ok := m.(playerMessage); ok && playerId == "" {
+			playerId = m.GetPlayerId()
+		}
+	}
+	if tableId != "" {
+		attrs = append(attrs, "tableId", tableId)
+	}
+	if playerId != "" {
+		attrs = append(attrs, "playerId", playerId)
+	}
+	level := slog.LevelInfo
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unimplemented:
+		level = slog.LevelError
+	case codes.Unavailable:
+		// A busy table or a server that is shutting down, logged like the 503s of REST.
+		level = slog.LevelWarn
+	default:
+	}
+	slog.Log(ctx, level, "Served call", attrs...)
+}
+
+// loggedStream carries the request id in its context and keeps the first message
+// received, so that the call can be logged with the table it was about.
+type loggedStream struct {
+	grpc.ServerStream
+	ctx context.Context //nolint: containedctx
+	req any
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggedStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && s.req == nil {
+		s.req = m
+	}
+	return err //nolint: wrapcheck
+}
@@ -0,0 +1,85 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"github.com/GRO4T/bjack-api/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// nolint: ireturn
+func setupLogged(t *testing.T) (*bgrpc.BlackjackServer, pb.BlackjackClient) {
+	t.Helper()
+	unaryLogging, streamLogging := bgrpc.LoggingInterceptors()
+	unaryRecovery, streamRecovery := bgrpc.RecoveryInterceptors()
+	return Setup(t,
+		grpc.ChainUnaryInterceptor(unaryLogging, unaryRecovery),
+		grpc.ChainStreamInterceptor(streamLogging, streamRecovery),
+	)
+}
+
+func TestGrpcApi_RequestIdEchoed(t *testing.T) {
+	// Arrange
+	_, client := setupLogged(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestid.Metadata, "abc-123")
+	var header metadata.MD
+
+	// Act
+	_, err := client.CreateGame(ctx, &emptypb.Empty{}, grpc.Header(&header))
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := header.Get(requestid.Metadata); len(ids) != 1 || ids[0] != "abc-123" {
+		t.Errorf("Expected the request id to be echoed; got %v", ids)
+	}
+}
+
+func TestGrpcApi_PanicInGameRecovered(t *testing.T) {
+	// Arrange
+	server, client := setupLogged(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	game.Deck = nil
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.PlayerAction(
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.PlayerActionRequest{TableId: testTableId, PlayerId: newPlayer.Id, Action: pb.Action_HIT},
+	)
+
+	// Assert
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal; got %v", err)
+	}
+}
+
+func TestRecoveryInterceptors(t *testing.T) {
+	// Arrange
+	unary, _ := bgrpc.RecoveryInterceptors()
+	handler := func(context.Context, any) (any, error) {
+		panic("bug")
+	}
+
+	// Act
+	_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, handler)
+
+	// Assert
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected Internal; got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsInterceptors record the latency of every call by method and status code
+// in a histogram registered with reg. The latency of a stream is the time it was open.
+func MetricsInterceptors(reg prometheus.Registerer) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	latency := promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bjack_grpc_request_duration_seconds",
+		Help:    "Latency of gRPC calls.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+	observe := func(method string, start time.Time, err error) {
+		latency.WithLabelValues(method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+	}
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		reply, err := handler(ctx, req)
+		observe(info.FullMethod, start, err)
+		return reply, err
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(info.FullMethod, start, err)
+		return err
+	}
+	return unary, stream
+}
@@ -0,0 +1,39 @@
+package grpc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestMetricsInterceptors(t *testing.T) {
+	// Arrange
+	reg := prometheus.NewRegistry()
+	unary, stream := bgrpc.MetricsInterceptors(reg)
+	_, client := Setup(t, grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+
+	// Act
+	_, _ = client.CreateGame(context.Background(), &emptypb.Empty{})
+	_, _ = client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: testTableId})
+
+	// Assert
+	out := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(out, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, series := range []string{
+		`bjack_grpc_request_duration_seconds_count{code="OK",method="` + pb.Blackjack_CreateGame_FullMethodName + `"} 1`,
+		`bjack_grpc_request_duration_seconds_count{code="NotFound",method="` + pb.Blackjack_GetGameState_FullMethodName + `"} 1`,
+	} {
+		if !strings.Contains(out.Body.String(), series) {
+			t.Errorf("Expected %v in\n%v", series, out.Body.String())
+		}
+	}
+}
@@ -2,43 +2,68 @@ package grpc
 
 import (
 	"context"
-	"crypto/rand"
-	"math/big"
-	"strconv"
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/GRO4T/bjack-api/auth"
 	"github.com/GRO4T/bjack-api/blackjack"
 	"github.com/GRO4T/bjack-api/constant"
+	"github.com/GRO4T/bjack-api/ids"
 	pb "github.com/GRO4T/bjack-api/proto"
-	"google.golang.org/grpc/codes"
+	"github.com/GRO4T/bjack-api/registry"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 type BlackjackServer struct {
 	pb.UnimplementedBlackjackServer
-	Games map[string]*blackjack.Blackjack
+	Signer *auth.Signer
+	Tables *registry.Registry
 }
 
-func NewServer() *BlackjackServer {
+func NewServer(signer *auth.Signer, tables *registry.Registry) *BlackjackServer {
 	return &BlackjackServer{
-		Games: map[string]*blackjack.Blackjack{},
+		Signer: signer,
+		Tables: tables,
 	}
 }
 
-func (s *BlackjackServer) CreateGame(context.Context, *emptypb.Empty) (*pb.CreateGameResponse, error) {
-	tableId := getRandomId()
-	newGame := blackjack.New(nil)
-	s.Games[tableId] = &newGame
-	return &pb.CreateGameResponse{TableId: tableId}, nil
+func (s *BlackjackServer) CreateGame(c context.Context, _ *emptypb.Empty) (*pb.CreateGameResponse, error) {
+	table, err := s.Tables.Create()
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+	return &pb.CreateGameResponse{TableId: table.Id}, nil
 }
 
-// nolint: gosec
 func (s *BlackjackServer) GetGameState(c context.Context, r *pb.GetGameStateRequest) (*pb.GetGameStateResponse, error) {
-	game, ok := s.Games[r.TableId]
-	if !ok {
-		return nil, status.Errorf(codes.NotFound, "Game not found")
+	if err := validateIds(r.TableId); err != nil {
+		return nil, err
+	}
+
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return nil, errorStatus(c, err)
 	}
 
+	ctx, cancel := context.WithTimeout(c, constant.CommandTimeout)
+	defer cancel()
+	var resp *pb.GetGameStateResponse
+	err = table.Do(ctx, func(game *blackjack.Blackjack) error {
+		resp = gameStateToPb(game)
+		resp.Version = table.Version()
+		return nil
+	})
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+	return resp, nil
+}
+
+// nolint: gosec
+func gameStateToPb(game *blackjack.Blackjack) *pb.GetGameStateResponse {
 	pbHands := []*pb.Hand{}
 	for _, cards := range game.Hands {
 		pbCards := []*pb.Card{}
@@ -53,13 +78,7 @@ func (s *BlackjackServer) GetGameState(c context.Context, r *pb.GetGameStateRequ
 
 	pbPlayers := []*pb.Player{}
 	for _, player := range game.Players {
-		pbPlayers = append(pbPlayers, &pb.Player{
-			Name:    player.Name,
-			IsReady: player.IsReady,
-			Chips:   int32(player.Chips),
-			Bet:     int32(player.Bet), //nolint: gosec
-			Outcome: pb.Outcome(player.Outcome),
-		})
+		pbPlayers = append(pbPlayers, playerToPb(*player))
 	}
 
 	return &pb.GetGameStateResponse{
@@ -67,66 +86,309 @@ func (s *BlackjackServer) GetGameState(c context.Context, r *pb.GetGameStateRequ
 		Players:       pbPlayers,
 		State:         pb.State(game.State),
 		CurrentPlayer: int32(game.CurrentPlayer),
-	}, nil
+	}
+}
+
+// nolint: gosec
+func playerToPb(player blackjack.Player) *pb.Player {
+	return &pb.Player{
+		Name:    player.Name,
+		IsReady: player.IsReady,
+		Chips:   int32(player.Chips),
+		Bet:     int32(player.Bet),
+		Outcome: pb.Outcome(player.Outcome),
+	}
 }
 
 func (s *BlackjackServer) AddPlayer(c context.Context, r *pb.AddPlayerRequest) (*pb.AddPlayerResponse, error) {
-	game, ok := s.Games[r.TableId]
-	if !ok {
-		return nil, status.Errorf(codes.NotFound, "Game not found")
+	if err := validateIds(r.TableId); err != nil {
+		return nil, err
+	}
+	if r.PlayerName == "" {
+		return nil, invalidArgument("playerName", "Player name cannot be empty")
 	}
-	newPlayer, err := game.AddPlayer("Bob")
+
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+	ctx, cancel := commandContext(c, r.ExpectedVersion)
+	defer cancel()
+	newPlayer, err := table.Join(ctx, r.PlayerName)
 	if err != nil {
-		return nil, status.Errorf(codes.FailedPrecondition, "Failed to add player: %v", err)
+		return nil, errorStatus(c, err)
 	}
-	return &pb.AddPlayerResponse{PlayerId: newPlayer.Id}, nil
+	token, err := s.Signer.Issue(r.TableId, newPlayer.Id)
+	if err != nil {
+		return nil, errorStatus(c, fmt.Errorf("failed to issue session token: %w", err))
+	}
+	return &pb.AddPlayerResponse{PlayerId: newPlayer.Id, Token: token}, nil
+}
+
+func (s *BlackjackServer) RemovePlayer(c context.Context, r *pb.RemovePlayerRequest) (*emptypb.Empty, error) {
+	if err := validateIds(r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+	if err := s.authorizePlayer(c, r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+
+	ctx, cancel := commandContext(c, r.ExpectedVersion)
+	defer cancel()
+	if err := table.Leave(ctx, r.PlayerId); err != nil {
+		return nil, errorStatus(c, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *BlackjackServer) GetPlayer(c context.Context, r *pb.GetPlayerRequest) (*pb.Player, error) {
+	if err := validateIds(r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+	if err := s.authorizePlayer(c, r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(c, constant.CommandTimeout)
+	defer cancel()
+	var resp *pb.Player
+	err = table.Do(ctx, func(game *blackjack.Blackjack) error {
+		for _, player := range game.Players {
+			if player.Id == r.PlayerId {
+				resp = playerToPb(*player)
+				return nil
+			}
+		}
+		return blackjack.ErrNotFound
+	})
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+	return resp, nil
 }
 
-// nolint: gosec
 func (s *BlackjackServer) TogglePlayerReady(c context.Context, r *pb.TogglePlayerReadyRequest) (*pb.Player, error) {
-	game, ok := s.Games[r.TableId]
-	if !ok {
-		return nil, status.Errorf(codes.NotFound, "Game not found")
+	if err := validateIds(r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+	if err := s.authorizePlayer(c, r.TableId, r.PlayerId); err != nil {
+		return nil, err
 	}
 
-	player, err := game.TogglePlayerReady(r.PlayerId)
+	table, err := s.Tables.Get(r.TableId)
 	if err != nil {
-		return nil, status.Errorf(codes.FailedPrecondition, "Failed to toggle readiness: %v", err)
+		return nil, errorStatus(c, err)
 	}
 
-	return &pb.Player{
-		Name:    player.Name,
-		IsReady: player.IsReady,
-		Chips:   int32(player.Chips),
-		Bet:     int32(player.Bet),
-		Outcome: pb.Outcome(player.Outcome),
-	}, nil
+	ctx, cancel := commandContext(c, r.ExpectedVersion)
+	defer cancel()
+	player, err := table.ToggleReady(ctx, r.PlayerId)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+	return playerToPb(player), nil
+}
+
+func (s *BlackjackServer) SetPlayerReady(c context.Context, r *pb.SetPlayerReadyRequest) (*pb.Player, error) {
+	if err := validateIds(r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+	if err := s.authorizePlayer(c, r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+
+	ctx, cancel := commandContext(c, r.ExpectedVersion)
+	defer cancel()
+	player, err := table.SetReady(ctx, r.PlayerId, r.Ready)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+	return playerToPb(player), nil
+}
+
+func (s *BlackjackServer) PlaceBet(c context.Context, r *pb.PlaceBetRequest) (*pb.Player, error) {
+	if err := validateIds(r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+	if err := s.authorizePlayer(c, r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+
+	ctx, cancel := commandContext(c, r.ExpectedVersion)
+	defer cancel()
+	player, err := table.PlaceBet(ctx, r.PlayerId, int(r.Amount))
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+	return playerToPb(player), nil
 }
 
 func (s *BlackjackServer) PlayerAction(c context.Context, r *pb.PlayerActionRequest) (*emptypb.Empty, error) {
-	game, ok := s.Games[r.TableId]
-	if !ok {
-		return nil, status.Errorf(codes.NotFound, "Game not found")
+	if err := validateIds(r.TableId, r.PlayerId); err != nil {
+		return nil, err
+	}
+	if err := s.authorizePlayer(c, r.TableId, r.PlayerId); err != nil {
+		return nil, err
 	}
 
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return nil, errorStatus(c, err)
+	}
+
+	var action blackjack.Action
 	switch r.Action {
 	case pb.Action_HIT:
-		if err := game.PlayerAction(r.PlayerId, blackjack.Hit); err != nil {
-			return nil, status.Errorf(codes.FailedPrecondition, "Invalid action")
-		}
+		action = blackjack.Hit
 	case pb.Action_STAND:
-		if err := game.PlayerAction(r.PlayerId, blackjack.Stand); err != nil {
-			return nil, status.Errorf(codes.FailedPrecondition, "Invalid action")
-		}
+		action = blackjack.Stand
+	default:
+		return nil, invalidArgument("action", "Invalid action")
+	}
+	ctx, cancel := commandContext(c, r.ExpectedVersion)
+	defer cancel()
+	if err := table.Act(ctx, r.PlayerId, action); err != nil {
+		return nil, errorStatus(c, err)
 	}
 
 	return &emptypb.Empty{}, nil
 }
 
-func getRandomId() string {
-	id, err := rand.Int(rand.Reader, big.NewInt(constant.MaxId))
+var pbEventTypes = map[registry.EventType]pb.EventType{
+	registry.Snapshot:           pb.EventType_SNAPSHOT,
+	registry.PlayerJoined:       pb.EventType_PLAYER_JOINED,
+	registry.PlayerLeft:         pb.EventType_PLAYER_LEFT,
+	registry.PlayerReadyToggled: pb.EventType_PLAYER_READY_TOGGLED,
+	registry.BetPlaced:          pb.EventType_BET_PLACED,
+	registry.PlayerActed:        pb.EventType_PLAYER_ACTED,
+	registry.TurnTimedOut:       pb.EventType_TURN_TIMED_OUT,
+	registry.TableClosed:        pb.EventType_TABLE_CLOSED,
+	registry.ServerShutdown:     pb.EventType_SERVER_SHUTDOWN,
+}
+
+func (s *BlackjackServer) WatchGame(r *pb.WatchGameRequest, stream pb.Blackjack_WatchGameServer) error {
+	if err := validateIds(r.TableId); err != nil {
+		return err
+	}
+
+	table, err := s.Tables.Get(r.TableId)
+	if err != nil {
+		return errorStatus(stream.Context(), err)
+	}
+
+	ctx, cancel := context.WithTimeout(stream.Context(), constant.CommandTimeout)
+	catchUp, events, unsubscribe, err := s.Tables.Watch(ctx, table, r.SinceSeq != nil, r.GetSinceSeq())
+	cancel()
+	if errors.Is(err, registry.ErrTableClosed) {
+		return sendGameEvent(stream, registry.Event{Type: registry.TableClosed})
+	}
 	if err != nil {
-		panic(err)
+		return errorStatus(stream.Context(), err)
+	}
+	defer unsubscribe()
+
+	lastSeq := r.GetSinceSeq()
+	for _, event := range catchUp {
+		if err := sendGameEvent(stream, event); err != nil {
+			return err
+		}
+		lastSeq = event.Seq
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case event, ok := <-events:
+			if !ok {
+				return newStatus(blackjack.CodeTableUnavailable, "Stopped watching the game")
+			}
+			if event.Seq <= lastSeq {
+				continue
+			}
+			if err := sendGameEvent(stream, event); err != nil {
+				return err
+			}
+			switch event.Type {
+			case registry.TableClosed:
+				return nil
+			case registry.ServerShutdown:
+				return newStatus(blackjack.CodeTableUnavailable, "Server is shutting down")
+			}
+		}
+	}
+}
+
+func sendGameEvent(stream pb.Blackjack_WatchGameServer, event registry.Event) error {
+	pbEvent := &pb.GameEvent{
+		Type:     pbEventTypes[event.Type],
+		PlayerId: event.PlayerId,
+		Seq:      event.Seq,
+		Version:  event.Version,
+	}
+	if event.Game != nil {
+		pbEvent.State = gameStateToPb(event.Game)
+	}
+	return stream.Send(pbEvent) //nolint: wrapcheck
+}
+
+// commandContext returns the context of a table command. If the request has an expected
+// version, the command fails unless the table is still at that version.
+func commandContext(c context.Context, expectedVersion *uint64) (context.Context, context.CancelFunc) {
+	if expectedVersion != nil {
+		c = registry.WithExpectedVersion(c, *expectedVersion)
+	}
+	return context.WithTimeout(c, constant.CommandTimeout)
+}
+
+// authorizePlayer checks the session token from the "authorization" metadata.
+func (s *BlackjackServer) authorizePlayer(c context.Context, tableId string, playerId string) error {
+	md, _ := metadata.FromIncomingContext(c)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return newStatus(blackjack.CodeUnauthenticated, "Missing session token")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return newStatus(blackjack.CodeUnauthenticated, "Missing session token")
+	}
+	if err := s.Signer.Authorize(token, tableId, playerId); err != nil {
+		if errors.Is(err, auth.ErrTokenMismatch) {
+			return newStatus(blackjack.CodePermissionDenied, err.Error())
+		}
+		return newStatus(blackjack.CodeUnauthenticated, err.Error())
+	}
+	return nil
+}
+
+func validateIds(tableId string, playerIds ...string) error {
+	if !ids.ValidTableId(tableId) {
+		return invalidArgument("tableId", "Invalid table id")
+	}
+	for _, playerId := range playerIds {
+		if !ids.ValidPlayerId(playerId) {
+			return invalidArgument("playerId", "Invalid player id")
+		}
 	}
-	return strconv.Itoa(int(id.Int64()))
+	return nil
 }
@@ -2,32 +2,48 @@ package grpc_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/GRO4T/bjack-api/auth"
 	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/constant"
 	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	"github.com/GRO4T/bjack-api/ids"
 	pb "github.com/GRO4T/bjack-api/proto"
+	"github.com/GRO4T/bjack-api/registry"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
+const (
+	testTableId  = "ABC234"
+	otherTableId = "XYZ789"
+)
+
 // nolint: ireturn
-func Setup(t *testing.T) (*bgrpc.BlackjackServer, pb.BlackjackClient) {
+func Setup(t *testing.T, options ...grpc.ServerOption) (*bgrpc.BlackjackServer, pb.BlackjackClient) {
 	t.Helper()
 	lis := bufconn.Listen(1024 * 1024)
 	t.Cleanup(func() {
 		lis.Close()
 	})
 
-	serviceRegistrar := grpc.NewServer()
+	serviceRegistrar := grpc.NewServer(options...)
 	t.Cleanup(func() {
 		serviceRegistrar.Stop()
 	})
-	server := bgrpc.NewServer()
+	server := bgrpc.NewServer(auth.NewSigner([]byte("secret"), time.Hour), registry.New())
 	pb.RegisterBlackjackServer(serviceRegistrar, server)
 
 	go func() {
@@ -56,6 +72,19 @@ func Setup(t *testing.T) (*bgrpc.BlackjackServer, pb.BlackjackClient) {
 	return server, client
 }
 
+func withSessionToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func authorizedContext(t *testing.T, server *bgrpc.BlackjackServer, tableId string, playerId string) context.Context {
+	t.Helper()
+	token, err := server.Signer.Issue(tableId, playerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return withSessionToken(context.Background(), token)
+}
+
 func TestGrpcApi_CreateGame(t *testing.T) {
 	// Arrange
 	_, client := Setup(t)
@@ -80,10 +109,10 @@ func TestGrpcApi_GetGameState(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	server.Games["1"] = &game
+	server.Tables.Put(testTableId, &game)
 
 	// Act
-	res, err := client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: "1"})
+	res, err := client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: testTableId})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -94,21 +123,174 @@ func TestGrpcApi_GetGameState(t *testing.T) {
 	}
 }
 
+func TestGrpcApi_GetGameStateWithInvalidTableId(t *testing.T) {
+	// Arrange
+	_, client := Setup(t)
+
+	// Act
+	_, err := client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: "1"})
+
+	// Assert
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument; got %v", err)
+	}
+}
+
 func TestGrpcApi_AddPlayer(t *testing.T) {
 	// Arrange
 	server, client := Setup(t)
 	game := blackjack.New(nil)
-	server.Games["1"] = &game
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.AddPlayer(context.Background(), &pb.AddPlayerRequest{TableId: testTableId, PlayerName: "Player 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if len(game.Players) != 1 {
+		t.Errorf("Expected 1 player; got %v", len(game.Players))
+	}
+	if game.Players[0].Name != "Player 1" {
+		t.Errorf("Expected player name Player 1; got %v", game.Players[0].Name)
+	}
+}
+
+func TestGrpcApi_AddPlayerWithEmptyName(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.AddPlayer(context.Background(), &pb.AddPlayerRequest{TableId: testTableId})
+
+	// Assert
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument; got %v", err)
+	}
+}
+
+func TestGrpcApi_AddPlayerWhenGameIsFull(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	for i := range constant.MaxPlayers {
+		if _, err := game.AddPlayer(fmt.Sprintf("Player %v", i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.AddPlayer(context.Background(), &pb.AddPlayerRequest{TableId: testTableId, PlayerName: "Player 4"})
+
+	// Assert
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition; got %v", err)
+	}
+}
+
+func TestGrpcApi_AddPlayerWithExpectedVersion(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+	state, err := client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: testTableId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.AddPlayer(context.Background(), &pb.AddPlayerRequest{
+		TableId:         testTableId,
+		PlayerName:      "Player 1",
+		ExpectedVersion: &state.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err = client.AddPlayer(context.Background(), &pb.AddPlayerRequest{
+		TableId:         testTableId,
+		PlayerName:      "Player 2",
+		ExpectedVersion: &state.Version,
+	})
+
+	// Assert
+	st := status.Convert(err)
+	if st.Code() != codes.Aborted {
+		t.Fatalf("Expected Aborted; got %v", err)
+	}
+	if info := errorInfo(t, st); info.Reason != string(blackjack.CodeVersionMismatch) {
+		t.Errorf("Expected %v reason; got %v", blackjack.CodeVersionMismatch, info)
+	}
+}
+
+func TestGrpcApi_RemovePlayer(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.RemovePlayer(
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.RemovePlayerRequest{TableId: testTableId, PlayerId: newPlayer.Id},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if len(game.Players) != 0 {
+		t.Errorf("Expected 0 players; got %v", len(game.Players))
+	}
+}
+
+func TestGrpcApi_RemoveMissingPlayer(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+	playerId, err := ids.NewPlayerId(func(string) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err = client.RemovePlayer(
+		authorizedContext(t, server, testTableId, playerId),
+		&pb.RemovePlayerRequest{TableId: testTableId, PlayerId: playerId},
+	)
+
+	// Assert
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected NotFound; got %v", err)
+	}
+}
+
+func TestGrpcApi_GetPlayer(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	_, _ = game.AddPlayer("Player 1")
+	newPlayer, _ := game.AddPlayer("Player 2")
+	server.Tables.Put(testTableId, &game)
 
 	// Act
-	_, err := client.AddPlayer(context.Background(), &pb.AddPlayerRequest{TableId: "1"})
+	player, err := client.GetPlayer(
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.GetPlayerRequest{TableId: testTableId, PlayerId: newPlayer.Id},
+	)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Assert
-	if len(server.Games["1"].Players) != 1 {
-		t.Errorf("Expected 1 player; got %v", len(server.Games["1"].Players))
+	if player.Name != "Player 2" {
+		t.Errorf("Expected Player 2; got %v", player.Name)
 	}
 }
 
@@ -117,22 +299,22 @@ func TestGrpcApi_TogglePlayerReadyWhenPlayerNotReady(t *testing.T) {
 	server, client := Setup(t)
 	game := blackjack.New(nil)
 	newPlayer, _ := game.AddPlayer("Player 1")
-	server.Games["1"] = &game
+	server.Tables.Put(testTableId, &game)
 
 	// Act
 	_, err := client.TogglePlayerReady(
-		context.Background(),
-		&pb.TogglePlayerReadyRequest{TableId: "1", PlayerId: newPlayer.Id},
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.TogglePlayerReadyRequest{TableId: testTableId, PlayerId: newPlayer.Id},
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Assert
-	if !server.Games["1"].Players[0].IsReady {
+	if !game.Players[0].IsReady {
 		t.Error("Player is not ready")
 	}
-	if server.Games["1"].State != blackjack.CardsDealt {
+	if game.State != blackjack.CardsDealt {
 		t.Error("Game is not in CardsDealt state")
 	}
 }
@@ -142,24 +324,124 @@ func TestGrpcApi_TogglePlayerReadyWhenPlayerReady(t *testing.T) {
 	server, client := Setup(t)
 	game := blackjack.New(nil)
 	newPlayer, _ := game.AddPlayer("Player 1")
-	server.Games["1"] = &game
-	server.Games["1"].Players[0].IsReady = true
+	server.Tables.Put(testTableId, &game)
+	game.Players[0].IsReady = true
 
 	// Act
 	_, err := client.TogglePlayerReady(
-		context.Background(),
-		&pb.TogglePlayerReadyRequest{TableId: "1", PlayerId: newPlayer.Id},
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.TogglePlayerReadyRequest{TableId: testTableId, PlayerId: newPlayer.Id},
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Assert
-	if server.Games["1"].Players[0].IsReady {
+	if game.Players[0].IsReady {
 		t.Error("Player is ready")
 	}
 }
 
+func TestGrpcApi_SetPlayerReady(t *testing.T) {
+	tests := []struct {
+		name          string
+		wasReady      bool
+		ready         bool
+		expectedState blackjack.State
+	}{
+		{"ready", false, true, blackjack.CardsDealt},
+		{"not ready", true, false, blackjack.WaitingForPlayers},
+		{"still not ready", false, false, blackjack.WaitingForPlayers},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			server, client := Setup(t)
+			game := blackjack.New(nil)
+			newPlayer, _ := game.AddPlayer("Player 1")
+			game.Players[0].IsReady = tt.wasReady
+			server.Tables.Put(testTableId, &game)
+
+			// Act
+			player, err := client.SetPlayerReady(
+				authorizedContext(t, server, testTableId, newPlayer.Id),
+				&pb.SetPlayerReadyRequest{TableId: testTableId, PlayerId: newPlayer.Id, Ready: tt.ready},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Assert
+			if player.IsReady != tt.ready {
+				t.Errorf("Expected ready %v; got %v", tt.ready, player.IsReady)
+			}
+			if game.State != tt.expectedState {
+				t.Errorf("Expected state %v; got %v", tt.expectedState, game.State)
+			}
+		})
+	}
+}
+
+func TestGrpcApi_PlaceBet(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	player, err := client.PlaceBet(
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.PlaceBetRequest{TableId: testTableId, PlayerId: newPlayer.Id, Amount: 10},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if player.Bet != 10 {
+		t.Errorf("Expected bet 10; got %v", player.Bet)
+	}
+}
+
+func TestGrpcApi_PlaceBetInvalidAmount(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.PlaceBet(
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.PlaceBetRequest{TableId: testTableId, PlayerId: newPlayer.Id, Amount: 1000},
+	)
+
+	// Assert
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument; got %v", err)
+	}
+}
+
+func TestGrpcApi_PlaceBetMissingSessionToken(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.PlaceBet(
+		context.Background(),
+		&pb.PlaceBetRequest{TableId: testTableId, PlayerId: newPlayer.Id, Amount: 10},
+	)
+
+	// Assert
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated; got %v", err)
+	}
+}
+
 func TestGrpcApi_PlayerAction(t *testing.T) {
 	// Arrange
 	server, client := Setup(t)
@@ -170,20 +452,254 @@ func TestGrpcApi_PlayerAction(t *testing.T) {
 		t.Fatal(err)
 	}
 	game.State = blackjack.CardsDealt
-	server.Games["1"] = &game
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err = client.PlayerAction(
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.PlayerActionRequest{TableId: testTableId, PlayerId: newPlayer.Id, Action: pb.Action_HIT},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if len(game.GetPlayerHand(0)) != 3 {
+		t.Errorf("Expected 3 cards; got %v", len(game.GetPlayerHand(0)))
+	}
+}
+
+func TestGrpcApi_PlayerActionBeforeCardsDealt(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err := client.PlayerAction(
+		authorizedContext(t, server, testTableId, newPlayer.Id),
+		&pb.PlayerActionRequest{TableId: testTableId, PlayerId: newPlayer.Id, Action: pb.Action_HIT},
+	)
+
+	// Assert
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition; got %v", err)
+	}
+}
+
+func TestGrpcApi_PlayerActionWithoutSessionToken(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	err := game.Deal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	server.Tables.Put(testTableId, &game)
 
 	// Act
 	_, err = client.PlayerAction(
 		context.Background(),
-		&pb.PlayerActionRequest{TableId: "1", PlayerId: newPlayer.Id, Action: pb.Action_HIT},
+		&pb.PlayerActionRequest{TableId: testTableId, PlayerId: newPlayer.Id, Action: pb.Action_HIT},
+	)
+
+	// Assert
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected Unauthenticated; got %v", err)
+	}
+	if len(game.GetPlayerHand(0)) != 2 {
+		t.Errorf("Expected 2 cards; got %v", len(game.GetPlayerHand(0)))
+	}
+}
+
+func TestGrpcApi_PlayerActionWithOtherTableSessionToken(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	err := game.Deal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	server.Tables.Put(testTableId, &game)
+
+	// Act
+	_, err = client.PlayerAction(
+		authorizedContext(t, server, otherTableId, newPlayer.Id),
+		&pb.PlayerActionRequest{TableId: testTableId, PlayerId: newPlayer.Id, Action: pb.Action_HIT},
 	)
+
+	// Assert
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied; got %v", err)
+	}
+}
+
+func TestGrpcApi_WatchGame(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	table := server.Tables.Put(testTableId, &game)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// Act
+	stream, err := client.WatchGame(ctx, &pb.WatchGameRequest{TableId: testTableId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPlayer, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := stream.Recv()
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Assert
-	if len(server.Games["1"].GetPlayerHand(0)) != 3 {
-		t.Errorf("Expected 3 cards; got %v", len(server.Games["1"].GetPlayerHand(0)))
+	if snapshot.Type != pb.EventType_SNAPSHOT || len(snapshot.State.Players) != 0 {
+		t.Errorf("Expected snapshot with 0 players; got %v", snapshot)
+	}
+	if event.Type != pb.EventType_PLAYER_JOINED || event.PlayerId != newPlayer.Id {
+		t.Errorf("Expected PLAYER_JOINED event for %v; got %v", newPlayer.Id, event)
+	}
+	if len(event.State.Players) != 1 {
+		t.Errorf("Expected 1 player; got %v", len(event.State.Players))
+	}
+}
+
+func TestGrpcApi_WatchGameResumed(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	table := server.Tables.Put(testTableId, &game)
+	first, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := table.Join(context.Background(), "Player 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	sinceSeq := uint64(1)
+
+	// Act
+	stream, err := client.WatchGame(ctx, &pb.WatchGameRequest{TableId: testTableId, SinceSeq: &sinceSeq})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if event.Type != pb.EventType_PLAYER_JOINED || event.PlayerId != second.Id || event.Seq != 2 {
+		t.Errorf("Expected the missed PLAYER_JOINED event for %v; got %v", second.Id, event)
+	}
+	if event.PlayerId == first.Id {
+		t.Error("Expected the seen event to be skipped")
+	}
+	if len(event.State.Players) != 2 {
+		t.Errorf("Expected 2 players; got %v", len(event.State.Players))
+	}
+}
+
+func TestGrpcApi_WatchGameStopsWhenClientCancels(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.WatchGame(ctx, &pb.WatchGameRequest{TableId: testTableId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	cancel()
+
+	// Assert
+	deadline := time.Now().Add(5 * time.Second)
+	for server.Tables.Subscribers(testTableId) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected subscription to be removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGrpcApi_WatchGameEndsWhenTableClosed(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+	stream, err := client.WatchGame(context.Background(), &pb.WatchGameRequest{TableId: testTableId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	server.Tables.Remove(testTableId)
+
+	// Assert
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != pb.EventType_TABLE_CLOSED {
+		t.Errorf("Expected TABLE_CLOSED event; got %v", event)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected end of stream; got %v", err)
+	}
+}
+
+func TestGrpcApi_WatchGameEndsOnShutdown(t *testing.T) {
+	// Arrange
+	server, client := Setup(t)
+	game := blackjack.New(nil)
+	server.Tables.Put(testTableId, &game)
+	stream, err := client.WatchGame(context.Background(), &pb.WatchGameRequest{TableId: testTableId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	if err := server.Tables.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != pb.EventType_SERVER_SHUTDOWN {
+		t.Errorf("Expected SERVER_SHUTDOWN event; got %v", event)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable; got %v", err)
 	}
 }
 
@@ -199,21 +715,22 @@ func TestGrpcApi_SimpleGame(t *testing.T) {
 	tableId := createGameResp.TableId
 
 	// Add player
-	addPlayerResp, err := client.AddPlayer(ctx, &pb.AddPlayerRequest{TableId: tableId})
+	addPlayerResp, err := client.AddPlayer(ctx, &pb.AddPlayerRequest{TableId: tableId, PlayerName: "Player 1"})
 	if err != nil {
 		t.Fatal(err)
 	}
 	playerId := addPlayerResp.PlayerId
+	playerCtx := withSessionToken(ctx, addPlayerResp.Token)
 
 	// Toggle player ready
-	_, err = client.TogglePlayerReady(ctx, &pb.TogglePlayerReadyRequest{TableId: tableId, PlayerId: playerId})
+	_, err = client.TogglePlayerReady(playerCtx, &pb.TogglePlayerReadyRequest{TableId: tableId, PlayerId: playerId})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Player hit
 	_, err = client.PlayerAction(
-		ctx,
+		playerCtx,
 		&pb.PlayerActionRequest{TableId: tableId, PlayerId: playerId, Action: pb.Action_HIT},
 	)
 	if err != nil {
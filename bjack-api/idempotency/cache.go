@@ -0,0 +1,122 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+)
+
+// MaxKeyLength is the length of the longest idempotency key accepted from clients.
+const MaxKeyLength = 255
+
+var (
+	ErrKeyReused = blackjack.NewError(blackjack.CodeIdempotencyKeyReused,
+		"idempotency key was already used for a different request")
+)
+
+// Cache remembers the outcome of requests by their idempotency key for a time window,
+// so that a retried request gets the first outcome instead of being executed again.
+// Requests are told apart from retries by a fingerprint of their content.
+type Cache[T any] struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]*entry[T]
+	// order holds the kept entries from the oldest, they expire in the same order.
+	order []*entry[T]
+}
+
+type entry[T any] struct {
+	key         string
+	fingerprint string
+	done        chan struct{}
+	result      T
+	kept        bool
+	expiresAt   time.Time
+}
+
+func NewCache[T any](ttl time.Duration) *Cache[T] {
+	return &Cache[T]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*entry[T]),
+	}
+}
+
+// Do returns the outcome kept for the key, or runs execute if there is none.
+// execute reports whether its outcome should be kept; outcomes that are not kept,
+// e.g. transient failures, let a retry run again. Duplicates that arrive while
+// the first request is still running wait for its outcome.
+// ErrKeyReused is returned if the key was used for a request with another fingerprint.
+func (c *Cache[T]) Do(ctx context.Context, key string, fingerprint string, execute func() (T, bool)) (T, bool, error) {
+	var zero T
+	for {
+		c.mu.Lock()
+		c.expire()
+		e, found := c.entries[key]
+		if !found {
+			e = &entry[T]{key: key, fingerprint: fingerprint, done: make(chan struct{})}
+			c.entries[key] = e
+			c.mu.Unlock()
+			return c.run(e, execute), false, nil
+		}
+		c.mu.Unlock()
+
+		if e.fingerprint != fingerprint {
+			return zero, false, ErrKeyReused
+		}
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return zero, false, fmt.Errorf("waiting for the first request: %w", ctx.Err())
+		}
+		if e.kept {
+			return e.result, true, nil
+		}
+	}
+}
+
+// Len returns the number of kept or running requests.
+func (c *Cache[T]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire()
+	return len(c.entries)
+}
+
+func (c *Cache[T]) run(e *entry[T], execute func() (T, bool)) T {
+	kept := false
+	defer func() {
+		c.mu.Lock()
+		if kept {
+			e.kept = true
+			e.expiresAt = c.now().Add(c.ttl)
+			c.order = append(c.order, e)
+		} else {
+			delete(c.entries, e.key)
+		}
+		c.mu.Unlock()
+		close(e.done)
+	}()
+	result, keep := execute()
+	e.result = result
+	kept = keep
+	return result
+}
+
+// expire removes the entries whose window has passed. The caller must hold the lock.
+func (c *Cache[T]) expire() {
+	now := c.now()
+	n := 0
+	for _, e := range c.order {
+		if now.Before(e.expiresAt) {
+			break
+		}
+		delete(c.entries, e.key)
+		n++
+	}
+	c.order = c.order[n:]
+}
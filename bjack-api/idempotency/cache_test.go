@@ -0,0 +1,135 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/idempotency"
+)
+
+func TestReplay(t *testing.T) {
+	// Arrange
+	cache := idempotency.NewCache[int](time.Hour)
+	calls := 0
+	execute := func() (int, bool) {
+		calls++
+		return calls, true
+	}
+	first, _, err := cache.Do(context.Background(), "key", "request", execute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	second, replayed, err := cache.Do(context.Background(), "key", "request", execute)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replayed || second != first || calls != 1 {
+		t.Errorf("Expected the first result to be replayed; got %v after %v calls", second, calls)
+	}
+}
+
+func TestKeyReusedForOtherRequest(t *testing.T) {
+	// Arrange
+	cache := idempotency.NewCache[int](time.Hour)
+	if _, _, err := cache.Do(context.Background(), "key", "request", func() (int, bool) { return 1, true }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, _, err := cache.Do(context.Background(), "key", "other request", func() (int, bool) { return 2, true })
+
+	// Assert
+	if !errors.Is(err, idempotency.ErrKeyReused) {
+		t.Errorf("Expected %v; got %v", idempotency.ErrKeyReused, err)
+	}
+}
+
+func TestResultNotKept(t *testing.T) {
+	// Arrange
+	cache := idempotency.NewCache[int](time.Hour)
+	calls := 0
+	execute := func() (int, bool) {
+		calls++
+		return calls, calls > 1
+	}
+	if _, _, err := cache.Do(context.Background(), "key", "request", execute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	result, replayed, err := cache.Do(context.Background(), "key", "request", execute)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed || result != 2 {
+		t.Errorf("Expected the request to run again; got %v", result)
+	}
+}
+
+func TestKeyExpires(t *testing.T) {
+	// Arrange
+	cache := idempotency.NewCache[int](-time.Minute)
+	if _, _, err := cache.Do(context.Background(), "key", "request", func() (int, bool) { return 1, true }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	result, replayed, err := cache.Do(context.Background(), "key", "other request", func() (int, bool) { return 2, true })
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed || result != 2 {
+		t.Errorf("Expected the key to be free again; got %v", result)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected expired keys to be removed; got %v", cache.Len())
+	}
+}
+
+func TestParallelDuplicatesRunOnce(t *testing.T) {
+	// Arrange
+	cache := idempotency.NewCache[int64](time.Hour)
+	var calls atomic.Int64
+	release := make(chan struct{})
+	execute := func() (int64, bool) {
+		<-release
+		return calls.Add(1), true
+	}
+	const n = 10
+	results := make([]int64, n)
+	var wg sync.WaitGroup
+
+	// Act
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _, _ = cache.Do(context.Background(), "key", "request", execute)
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	// Assert
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 call; got %v", calls.Load())
+	}
+	for _, result := range results {
+		if result != 1 {
+			t.Errorf("Expected every duplicate to get the first result; got %v", results)
+			break
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrNoFreeId = errors.New("failed to generate a free id")
+)
+
+const (
+	// Crockford-like alphabet without I, O, 0 and 1 which are easy to confuse.
+	alphabet       = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	TableIdLength  = 6
+	PlayerIdLength = 26
+	playerIdBytes  = 16
+	maxAttempts    = 10
+)
+
+var encoding = base32.NewEncoding(alphabet).WithPadding(base32.NoPadding)
+
+// NewTableId returns a short code that is easy to share with other players.
+// Codes for which taken returns true are skipped.
+func NewTableId(taken func(string) bool) (string, error) {
+	return unique(newTableCode, taken)
+}
+
+// NewPlayerId returns a 128-bit random id.
+// Ids for which taken returns true are skipped.
+func NewPlayerId(taken func(string) bool) (string, error) {
+	return unique(newPlayerId, taken)
+}
+
+func ValidTableId(id string) bool {
+	return len(id) == TableIdLength && inAlphabet(id)
+}
+
+func ValidPlayerId(id string) bool {
+	return len(id) == PlayerIdLength && inAlphabet(id)
+}
+
+func unique(generate func() string, taken func(string) bool) (string, error) {
+	for range maxAttempts {
+		id := generate()
+		if taken == nil || !taken(id) {
+			return id, nil
+		}
+	}
+	return "", ErrNoFreeId
+}
+
+func newTableCode() string {
+	var sb strings.Builder
+	for range TableIdLength {
+		i, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			panic(err)
+		}
+		sb.WriteByte(alphabet[i.Int64()])
+	}
+	return sb.String()
+}
+
+func newPlayerId() string {
+	b := make([]byte, playerIdBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return encoding.EncodeToString(b)
+}
+
+func inAlphabet(id string) bool {
+	for _, c := range id {
+		if !strings.ContainsRune(alphabet, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package ids_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/ids"
+)
+
+func TestNewTableId(t *testing.T) {
+	id, err := ids.NewTableId(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ids.ValidTableId(id) {
+		t.Errorf("Expected valid table id; got %v", id)
+	}
+}
+
+func TestNewPlayerId(t *testing.T) {
+	id, err := ids.NewPlayerId(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ids.ValidPlayerId(id) {
+		t.Errorf("Expected valid player id; got %v", id)
+	}
+}
+
+func TestNewTableIdSkipsTakenIds(t *testing.T) {
+	// Arrange
+	taken := map[string]bool{}
+	attempts := 0
+	isTaken := func(id string) bool {
+		attempts++
+		if attempts < 3 {
+			taken[id] = true
+			return true
+		}
+		return taken[id]
+	}
+
+	// Act
+	id, err := ids.NewTableId(isTaken)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taken[id] {
+		t.Errorf("Expected a free id; got taken %v", id)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts; got %v", attempts)
+	}
+}
+
+func TestNewTableIdWhenAllIdsTaken(t *testing.T) {
+	_, err := ids.NewTableId(func(string) bool { return true })
+	if !errors.Is(err, ids.ErrNoFreeId) {
+		t.Errorf("Expected ErrNoFreeId; got %v", err)
+	}
+}
+
+func TestValidTableId(t *testing.T) {
+	cases := map[string]bool{
+		"ABC234":  true,
+		"abc234":  false,
+		"ABC23":   false,
+		"ABC2345": false,
+		"ABCD10":  false,
+		"1":       false,
+		"":        false,
+	}
+	for id, expected := range cases {
+		if ids.ValidTableId(id) != expected {
+			t.Errorf("Expected ValidTableId(%q) to be %v", id, expected)
+		}
+	}
+}
+
+func TestValidPlayerId(t *testing.T) {
+	cases := map[string]bool{
+		"ABCDEFGHJKLMNPQRSTUVWXYZ23": true,
+		"ABCDEFGHJKLMNPQRSTUVWXYZ2":  false,
+		"ABCDEFGHJKLMNPQRSTUVWXYZ21": false,
+		"ABC234":                     false,
+	}
+	for id, expected := range cases {
+		if ids.ValidPlayerId(id) != expected {
+			t.Errorf("Expected ValidPlayerId(%q) to be %v", id, expected)
+		}
+	}
+}
@@ -1,80 +1,271 @@
-// TODO: Document the API using Swagger
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/GRO4T/bjack-api/auth"
+	"github.com/GRO4T/bjack-api/config"
+	"github.com/GRO4T/bjack-api/constant"
 	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	"github.com/GRO4T/bjack-api/idempotency"
+	"github.com/GRO4T/bjack-api/metrics"
 	pb "github.com/GRO4T/bjack-api/proto"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/GRO4T/bjack-api/requestid"
 	"github.com/GRO4T/bjack-api/rest"
+	"github.com/GRO4T/bjack-api/server"
+	"github.com/GRO4T/bjack-api/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 const (
-	ServerAddr = "0.0.0.0:8000"
+	// How often the gRPC health service checks whether the server is ready.
+	readinessInterval = 5 * time.Second
 )
 
-func grpcServer() {
-	listener, err := net.Listen("tcp", ServerAddr) //nolint:gosec
+func loadConfig() config.Config {
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(2) //nolint: mnd
+	}
+	return cfg
+}
+
+func newStore(cfg config.Config) *store.FileStore {
+	s, err := store.NewFileStore(cfg.DataDir)
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to open store: %v", err))
+		os.Exit(1)
+	}
+	return s
+}
+
+func newSigner(cfg config.Config) *auth.Signer {
+	ttl := time.Duration(cfg.Timeouts.SessionToken)
+	if cfg.SessionKey == "" {
+		slog.Warn("SESSION_KEY not provided, session tokens will not survive a restart")
+		return auth.NewSigner(auth.NewRandomKey(), ttl)
+	}
+	return auth.NewSigner([]byte(cfg.SessionKey), ttl)
+}
+
+// newGrpcServer serves the Blackjack service together with the standard health service
+// and server reflection, so that tools like grpcurl can discover the API.
+func newGrpcServer(
+	cfg config.Config,
+	reg prometheus.Registerer,
+	healthServer *health.Server,
+	blackjackServer *bgrpc.BlackjackServer,
+) *grpc.Server {
+	cache := idempotency.NewCache[bgrpc.SavedReply](time.Duration(cfg.Timeouts.Idempotency))
+	unaryMetrics, streamMetrics := bgrpc.MetricsInterceptors(reg)
+	unaryLogging, streamLogging := bgrpc.LoggingInterceptors()
+	unaryRecovery, streamRecovery := bgrpc.RecoveryInterceptors()
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			unaryMetrics,
+			unaryLogging,
+			unaryRecovery,
+			bgrpc.IdempotencyInterceptor(cache),
+		),
+		grpc.ChainStreamInterceptor(
+			streamMetrics,
+			streamLogging,
+			streamRecovery,
+		),
+	)
+	pb.RegisterBlackjackServer(s, blackjackServer)
+	healthpb.RegisterHealthServer(s, healthServer)
+	reflection.Register(s)
+	return s
+}
+
+// newRestHandler serves the hand-written REST API used by the UI
+// together with the REST mapping of the gRPC API under server.GatewayPrefix.
+func newRestHandler(
+	cfg config.Config,
+	reg *prometheus.Registry,
+	api *rest.RestApi,
+	blackjackServer *bgrpc.BlackjackServer,
+) http.Handler {
+	gateway, err := server.Gateway(context.Background(), blackjackServer)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	mux := api.Handler()
+	mux.Handle(server.GatewayPrefix, gateway)
+	promauto.With(reg).NewGaugeFunc(prometheus.GaugeOpts{Name: "bjack_websockets", Help: "Number of open websockets."},
+		func() float64 {
+			return float64(api.OpenWebsockets())
+		})
+	cache := idempotency.NewCache[rest.SavedResponse](time.Duration(cfg.Timeouts.Idempotency))
+	handler := rest.Instrument(reg, mux, rest.Logged(mux, rest.Recovered(rest.Idempotent(cache, mux))))
+
+	return cors.New(cors.Options{
+		AllowedOrigins: cfg.CorsOrigins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "If-Match", "Idempotency-Key", requestid.Header},
+		ExposedHeaders: []string{"ETag", "Idempotent-Replayed", requestid.Header},
+	}).Handler(handler)
+}
+
+// serveGrpc serves gRPC on its own port, for deployments that keep the protocols apart.
+func serveGrpc(s *grpc.Server, addr string) {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to listen: %v", err))
+		os.Exit(1)
 	}
-	s := grpc.NewServer()
-	pb.RegisterBlackjackServer(s, bgrpc.NewServer())
-	slog.Info(fmt.Sprintf("Starting gRPC server on %s", ServerAddr))
+	slog.Info(fmt.Sprintf("Starting gRPC server on %s", addr))
 	if err := s.Serve(listener); err != nil {
 		slog.Error(fmt.Sprintf("Failed to serve: %v", err))
+		os.Exit(1)
 	}
 }
 
 // nolint: mnd
-func restApiServer() {
-	api := rest.NewApi()
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/tables", api.CreateGame)
-	mux.HandleFunc("/tables/{tableId}", api.GetGameState)
-	mux.HandleFunc("/tables/ready/{tableId}/{playerId}", api.TogglePlayerReady)
-	mux.HandleFunc("/tables/players/{tableId}", api.AddPlayer)
-	mux.HandleFunc("/tables/players/{tableId}/{playerId}", api.RemovePlayer)
-	mux.HandleFunc("/tables/{tableId}/{playerId}", api.PlayerAction)
-	mux.HandleFunc("/state-updates/{tableId}", api.AddStateObserver)
-
-	uiUrl, ok := os.LookupEnv("UI_URL")
-	if !ok {
-		slog.Error("UI_URL not provided")
+func newHttpServer(cfg config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           cfg.Addr,
+		Handler:        handler,
+		ReadTimeout:    time.Duration(cfg.Timeouts.Read),
+		WriteTimeout:   time.Duration(cfg.Timeouts.Write),
+		MaxHeaderBytes: 1 << 20,
+	}
+}
+
+func serveHttp(s *http.Server) {
+	slog.Info(fmt.Sprintf("Starting server on %s", s.Addr))
+	if err := s.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		slog.Error(err.Error())
 		os.Exit(1)
 	}
-	corsMux := cors.New(cors.Options{
-		AllowedOrigins: []string{uiUrl},
-		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
-	}).Handler(mux)
+}
 
-	s := &http.Server{
-		Addr:           ServerAddr,
-		Handler:        corsMux,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
+// shutdown reports the server as not ready, stops taking new tables, gives rounds in progress
+// until the shutdown timeout to finish, saves the tables and tells their watchers that the
+// server is going away.
+// Only then the servers are stopped, so that the goodbyes reach the clients.
+func shutdown(
+	cfg config.Config,
+	api *rest.RestApi,
+	healthServer *health.Server,
+	httpServer *http.Server,
+	grpcServer *grpc.Server,
+) {
+	tables := api.Tables
+	slog.Info("Shutting down")
+	healthServer.Shutdown()
+	tables.Drain()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Timeouts.Shutdown))
+	defer cancel()
+	if err := tables.WaitForRounds(ctx); err != nil {
+		slog.Warn(fmt.Sprintf("Not waiting for rounds any longer: %v", err))
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), constant.CommandTimeout)
+	defer cancel()
+	if err := tables.Shutdown(ctx); err != nil {
+		slog.Error(err.Error())
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Duration(cfg.Timeouts.Write))
+	defer cancel()
+	if err := api.WaitForWebsockets(ctx); err != nil {
+		slog.Warn(err.Error())
+	}
+	if err := httpServer.Shutdown(ctx); err != nil {
+		slog.Error(fmt.Sprintf("Failed to shut down server: %v", err))
+	}
+	if cfg.GrpcAddr != "" {
+		grpcServer.GracefulStop()
+	} else {
+		// GracefulStop does not support connections served through the HTTP server.
+		// Watchers are gone by now, so only unary calls in flight could be cut short.
+		grpcServer.Stop()
 	}
-	slog.Info(fmt.Sprintf("Starting REST server on %s", ServerAddr))
-	slog.Error(s.ListenAndServe().Error())
 }
 
 func main() {
-	slog.SetLogLoggerLevel(slog.LevelDebug)
-	isGrpc := flag.Bool("grpc", false, "Start gRPC server instead of REST")
-	flag.Parse()
-	if *isGrpc {
-		grpcServer()
+	cfg := loadConfig()
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	slog.SetDefault(cfg.Log.Logger(os.Stderr))
+	for _, warning := range cfg.Warnings {
+		slog.Warn(warning)
+	}
+
+	fileStore := newStore(cfg)
+	tables := registry.New(
+		registry.WithTurnTimeout(time.Duration(cfg.Timeouts.Turn)),
+		registry.WithRules(cfg.Rules),
+		registry.WithStore(fileStore),
+	)
+	if err := tables.Restore(); err != nil {
+		slog.Error(fmt.Sprintf("Failed to restore tables: %v", err))
+		os.Exit(1)
+	}
+	slog.Info(fmt.Sprintf("Restored %d tables", tables.Len()))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	janitor := registry.NewJanitor(tables, time.Duration(cfg.Timeouts.TableTTL), time.Duration(cfg.Timeouts.JanitorInterval))
+	go janitor.Run(ctx)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	metrics.RegisterTables(reg, tables)
+	metrics.RegisterJanitor(reg, janitor)
+	signer := newSigner(cfg)
+	blackjackServer := bgrpc.NewServer(signer, tables)
+	healthServer := health.NewServer()
+	go bgrpc.WatchReadiness(ctx, healthServer, tables.Ready, readinessInterval)
+	grpcServer := newGrpcServer(cfg, reg, healthServer, blackjackServer)
+	api := rest.NewApi(signer, tables, rest.WithMetrics(promhttp.HandlerFor(reg, promhttp.HandlerOpts{})))
+	restHandler := newRestHandler(cfg, reg, api, blackjackServer)
+	// By default gRPC shares the port with REST. A gRPC address moves it to a port of its own.
+	var httpServer *http.Server
+	if cfg.GrpcAddr != "" {
+		go serveGrpc(grpcServer, cfg.GrpcAddr)
+		httpServer = newHttpServer(cfg, restHandler)
 	} else {
-		restApiServer()
+		httpServer = newHttpServer(cfg, server.Handler(grpcServer, restHandler))
+	}
+	go serveHttp(httpServer)
+
+	<-ctx.Done()
+	stop()
+	shutdown(cfg, api, healthServer, httpServer, grpcServer)
+	if err := fileStore.Close(); err != nil {
+		slog.Error(fmt.Sprintf("Failed to close store: %v", err))
+		os.Exit(1)
 	}
+	slog.Info("Server stopped")
 }
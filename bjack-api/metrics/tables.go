@@ -0,0 +1,87 @@
+// Package metrics exposes the state of the tables and the games played at them to Prometheus.
+package metrics
+
+import (
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var outcomeLabels = map[blackjack.Outcome]string{
+	blackjack.Undecided: "undecided",
+	blackjack.Win:       "win",
+	blackjack.Lose:      "lose",
+	blackjack.Push:      "push",
+}
+
+// Tables are the metrics of the games played in a registry.
+type Tables struct {
+	Rounds       prometheus.Counter
+	Outcomes     *prometheus.CounterVec
+	ChipsWagered prometheus.Counter
+	ChipsPaid    prometheus.Counter
+}
+
+// RegisterTables registers the metrics of the tables with r and keeps them up to date
+// by listening to their events.
+func RegisterTables(r prometheus.Registerer, tables *registry.Registry) *Tables {
+	f := promauto.With(r)
+	f.NewGaugeFunc(prometheus.GaugeOpts{Name: "bjack_tables", Help: "Number of open tables."}, func() float64 {
+		return float64(tables.Len())
+	})
+	f.NewGaugeFunc(prometheus.GaugeOpts{Name: "bjack_players", Help: "Number of players seated at all tables."},
+		func() float64 {
+			return float64(tables.Players())
+		})
+	f.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "bjack_subscribers",
+		Help: "Number of clients watching tables over websockets, event streams and gRPC.",
+	}, func() float64 {
+		return float64(tables.AllSubscribers())
+	})
+	m := &Tables{
+		Rounds: f.NewCounter(prometheus.CounterOpts{
+			Name: "bjack_rounds_total", Help: "Number of rounds played to the end.",
+		}),
+		Outcomes: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "bjack_outcomes_total", Help: "Number of hands by outcome.",
+		}, []string{"outcome"}),
+		ChipsWagered: f.NewCounter(prometheus.CounterOpts{
+			Name: "bjack_chips_wagered_total", Help: "Chips bet in rounds played to the end.",
+		}),
+		ChipsPaid: f.NewCounter(prometheus.CounterOpts{
+			Name: "bjack_chips_paid_total", Help: "Chips won by players from the dealer.",
+		}),
+	}
+	tables.AddListener(m.observe)
+	return m
+}
+
+// RegisterJanitor registers the number of idle tables evicted by j with r.
+func RegisterJanitor(r prometheus.Registerer, j *registry.Janitor) {
+	promauto.With(r).NewCounterFunc(prometheus.CounterOpts{
+		Name: "bjack_tables_evicted_total", Help: "Number of idle tables evicted.",
+	}, func() float64 {
+		return float64(j.Evicted())
+	})
+}
+
+// observe counts a round once the event that finished it is published.
+// A finished game does not change any more, so this happens once per round.
+func (m *Tables) observe(event registry.Event) {
+	if event.Game == nil || event.Game.State != blackjack.Finished {
+		return
+	}
+	if event.Type != registry.PlayerActed && event.Type != registry.TurnTimedOut {
+		return
+	}
+	m.Rounds.Inc()
+	for _, player := range event.Game.Players {
+		m.Outcomes.WithLabelValues(outcomeLabels[player.Outcome]).Inc()
+		m.ChipsWagered.Add(float64(player.Bet))
+		if player.Outcome == blackjack.Win {
+			m.ChipsPaid.Add(float64(player.Bet))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package metrics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/metrics"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTablesCountFinishedRound(t *testing.T) {
+	// Arrange
+	reg := prometheus.NewRegistry()
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	m := metrics.RegisterTables(reg, tables)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	player, err := table.Join(ctx, "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.PlaceBet(ctx, player.Id, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.ToggleReady(ctx, player.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err = table.Act(ctx, player.Id, blackjack.Stand)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rounds := testutil.ToFloat64(m.Rounds); rounds != 1 {
+		t.Errorf("Expected 1 round; got %v", rounds)
+	}
+	if wagered := testutil.ToFloat64(m.ChipsWagered); wagered != 10 {
+		t.Errorf("Expected 10 chips wagered; got %v", wagered)
+	}
+	wins := testutil.ToFloat64(m.Outcomes.WithLabelValues("win"))
+	outcomes := wins + testutil.ToFloat64(m.Outcomes.WithLabelValues("lose")) +
+		testutil.ToFloat64(m.Outcomes.WithLabelValues("push"))
+	if outcomes != 1 {
+		t.Errorf("Expected 1 outcome; got %v", outcomes)
+	}
+	if paid := testutil.ToFloat64(m.ChipsPaid); paid != 10*wins {
+		t.Errorf("Expected chips to be paid for a win only; got %v", paid)
+	}
+	expected := `
+# HELP bjack_players Number of players seated at all tables.
+# TYPE bjack_players gauge
+bjack_players 1
+# HELP bjack_tables Number of open tables.
+# TYPE bjack_tables gauge
+bjack_tables 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "bjack_tables", "bjack_players"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJanitorCountsEvictedTables(t *testing.T) {
+	// Arrange
+	reg := prometheus.NewRegistry()
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	if _, err := tables.Create(); err != nil {
+		t.Fatal(err)
+	}
+	janitor := registry.NewJanitor(tables, time.Minute, time.Minute)
+	metrics.RegisterJanitor(reg, janitor)
+
+	// Act
+	janitor.Sweep(time.Now().Add(2 * time.Minute))
+
+	// Assert
+	expected := `
+# HELP bjack_tables_evicted_total Number of idle tables evicted.
+# TYPE bjack_tables_evicted_total counter
+bjack_tables_evicted_total 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Janitor periodically removes tables that were not changed by any command
+// for longer than the TTL. This covers both abandoned games and tables
+// that never got any players.
+type Janitor struct {
+	tables   *Registry
+	ttl      time.Duration
+	interval time.Duration
+	evicted  atomic.Int64
+}
+
+func NewJanitor(tables *Registry, ttl time.Duration, interval time.Duration) *Janitor {
+	return &Janitor{
+		tables:   tables,
+		ttl:      ttl,
+		interval: interval,
+	}
+}
+
+// Run sweeps idle tables every interval until ctx is done.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if n := j.Sweep(now); n > 0 {
+				slog.Info("Evicted idle tables", "count", n, "total", j.Evicted())
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sweep removes tables idle at the given time and returns how many were removed.
+func (j *Janitor) Sweep(now time.Time) int {
+	n := 0
+	for _, table := range j.tables.All() {
+		removed, err := j.tables.RemoveIdle(context.Background(), table.Id, now, j.ttl)
+		// Tables removed or stopped meanwhile are gone already.
+		if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrTableClosed) {
+			slog.Error(fmt.Sprintf("Failed to evict table %v: %v", table.Id, err))
+		}
+		if removed {
+			slog.Debug("Evicted idle table", "tableId", table.Id)
+			n++
+		}
+	}
+	j.evicted.Add(int64(n))
+	return n
+}
+
+// Evicted returns the number of tables removed since the janitor was created.
+func (j *Janitor) Evicted() int64 {
+	return j.evicted.Load()
+}
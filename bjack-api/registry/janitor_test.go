@@ -0,0 +1,65 @@
+package registry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/registry"
+)
+
+func TestSweepRemovesIdleTables(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	idle, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := []registry.Event{}
+	tables.AddListener(func(event registry.Event) {
+		if event.Type == registry.TableClosed {
+			closed = append(closed, event)
+		}
+	})
+	janitor := registry.NewJanitor(tables, time.Minute, time.Minute)
+
+	// Act
+	n := janitor.Sweep(time.Now().Add(2 * time.Minute))
+
+	// Assert
+	if n != 1 || janitor.Evicted() != 1 {
+		t.Errorf("Expected 1 evicted table; got %v (total %v)", n, janitor.Evicted())
+	}
+	if tables.Len() != 0 {
+		t.Errorf("Expected 0 tables; got %v", tables.Len())
+	}
+	if len(closed) != 1 || closed[0].TableId != idle.Id {
+		t.Errorf("Expected TableClosed event for %v; got %+v", idle.Id, closed)
+	}
+}
+
+func TestSweepKeepsActiveTables(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	janitor := registry.NewJanitor(tables, time.Minute, time.Minute)
+	if _, err := table.Join(context.Background(), "Player 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	n := janitor.Sweep(time.Now().Add(30 * time.Second))
+
+	// Assert
+	if n != 0 {
+		t.Errorf("Expected 0 evicted tables; got %v", n)
+	}
+	if tables.Len() != 1 {
+		t.Errorf("Expected 1 table; got %v", tables.Len())
+	}
+}
@@ -0,0 +1,254 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/ids"
+	"github.com/GRO4T/bjack-api/store"
+)
+
+var (
+	ErrNotFound = blackjack.NewError(blackjack.CodeTableNotFound, "table not found")
+)
+
+// Registry is a concurrency-safe collection of tables
+// shared by the REST and gRPC servers.
+type Registry struct {
+	mu          sync.RWMutex
+	tables      map[string]*Table
+	listeners   []func(Event)
+	turnTimeout time.Duration
+	rules       blackjack.Rules
+	store       store.Store
+	subsMu      sync.Mutex
+	subscribers map[string]map[*subscription]struct{}
+	history     map[string]*history
+	draining    atomic.Bool
+}
+
+// WithTurnTimeout makes tables stand on behalf of players
+// who do not act within d. Turn timeouts are disabled by default.
+func WithTurnTimeout(d time.Duration) func(*Registry) {
+	return func(r *Registry) {
+		r.turnTimeout = d
+	}
+}
+
+// WithRules sets the rules of the tables created by Create. blackjack.DefaultRules are used by default.
+func WithRules(rules blackjack.Rules) func(*Registry) {
+	return func(r *Registry) {
+		r.rules = rules
+	}
+}
+
+// WithStore makes tables save their game to s after every change.
+func WithStore(s store.Store) func(*Registry) {
+	return func(r *Registry) {
+		r.store = s
+	}
+}
+
+func New(options ...func(*Registry)) *Registry {
+	r := &Registry{
+		tables:      map[string]*Table{},
+		subscribers: map[string]map[*subscription]struct{}{},
+		history:     map[string]*history{},
+		rules:       blackjack.DefaultRules,
+	}
+	for _, o := range options {
+		o(r)
+	}
+	return r
+}
+
+// AddListener registers f to be called after the state of any table changes.
+// Listeners are called from the table goroutine before the command's sender gets a reply.
+func (r *Registry) AddListener(f func(Event)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, f)
+}
+
+// Create starts a new game under a fresh table id.
+func (r *Registry) Create() (*Table, error) {
+	if r.Draining() {
+		return nil, ErrShuttingDown
+	}
+	game := blackjack.New(nil)
+	game.Rules = r.rules
+	// The game belongs to the table goroutine once put, so a copy is saved.
+	initial := game.Clone()
+	r.mu.Lock()
+	tableId, err := ids.NewTableId(func(id string) bool {
+		_, ok := r.tables[id]
+		return ok
+	})
+	if err != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("failed to generate table id: %w", err)
+	}
+	table := r.put(tableId, &game, 0)
+	r.mu.Unlock()
+	// Saving is slow, so other tables are not held up by it. No command can change the game
+	// before it is saved, as the id is not known until Create returns.
+	if r.store != nil {
+		if err := r.store.Save(tableId, initial, 0); err != nil {
+			slog.Error(fmt.Sprintf("Failed to save table %v: %v", tableId, err))
+		}
+	}
+	return table, nil
+}
+
+// Restore registers games loaded from the store.
+func (r *Registry) Restore() error {
+	if r.store == nil {
+		return nil
+	}
+	records, err := r.store.LoadAll()
+	if err != nil {
+		return fmt.Errorf("failed to load tables: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for tableId, record := range records {
+		// Versions keep growing across restarts, so that clients cannot match a version from before.
+		r.put(tableId, record.Game, record.Version)
+	}
+	return nil
+}
+
+// Put registers an existing game under the given id, replacing any previous table.
+// The game must not be accessed directly afterwards.
+func (r *Registry) Put(tableId string, game *blackjack.Blackjack) *Table {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.put(tableId, game, 0)
+}
+
+func (r *Registry) Get(tableId string) (*Table, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	table, ok := r.tables[tableId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return table, nil
+}
+
+// Remove closes the table, frees its id and publishes a TableClosed event.
+func (r *Registry) Remove(tableId string) {
+	r.mu.Lock()
+	table, ok := r.tables[tableId]
+	if ok {
+		table.Close()
+		delete(r.tables, tableId)
+	}
+	r.mu.Unlock()
+	if ok {
+		r.removed(table)
+	}
+}
+
+// RemoveIdle removes the table like Remove if no command changed it for at least ttl
+// at the given time, and reports whether it did.
+func (r *Registry) RemoveIdle(ctx context.Context, tableId string, now time.Time, ttl time.Duration) (bool, error) {
+	table, err := r.Get(tableId)
+	if err != nil {
+		return false, err
+	}
+	idle, err := table.closeIfIdle(ctx, now, ttl)
+	if err != nil || !idle {
+		return false, err
+	}
+	r.mu.Lock()
+	// The id may have been given to another table meanwhile.
+	if r.tables[tableId] == table {
+		delete(r.tables, tableId)
+	}
+	r.mu.Unlock()
+	r.removed(table)
+	return true, nil
+}
+
+// removed deletes a closed table from the store and publishes a TableClosed event.
+func (r *Registry) removed(table *Table) {
+	// A command in progress may still save the game, which would bring the table back on Restore.
+	// The lock is released first, as the command publishes its event under it.
+	table.Wait()
+	if r.store != nil {
+		if err := r.store.Delete(table.Id); err != nil {
+			slog.Error(fmt.Sprintf("Failed to delete table %v: %v", table.Id, err))
+		}
+	}
+	r.notify(Event{
+		TableId: table.Id,
+		Type:    TableClosed,
+		Seq:     table.seq.Add(1),
+		Version: table.Version(),
+	})
+}
+
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tables)
+}
+
+// Players returns the number of players seated at all tables.
+func (r *Registry) Players() int {
+	n := 0
+	for _, table := range r.All() {
+		n += table.Players()
+	}
+	return n
+}
+
+// All returns a snapshot of the registered tables.
+func (r *Registry) All() []*Table {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tables := make([]*Table, 0, len(r.tables))
+	for _, table := range r.tables {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// Close stops all tables.
+func (r *Registry) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, table := range r.tables {
+		table.Close()
+		delete(r.tables, id)
+	}
+}
+
+func (r *Registry) put(tableId string, game *blackjack.Blackjack, version uint64) *Table {
+	if old, ok := r.tables[tableId]; ok {
+		old.Close()
+		// The new table numbers its events from scratch.
+		r.subsMu.Lock()
+		delete(r.history, tableId)
+		r.subsMu.Unlock()
+	}
+	table := newTable(tableId, game, version, r.notify, r.Draining, r.turnTimeout, r.store)
+	r.tables[tableId] = table
+	return table
+}
+
+func (r *Registry) notify(event Event) {
+	r.mu.RLock()
+	listeners := r.listeners
+	r.mu.RUnlock()
+	for _, listener := range listeners {
+		listener(event)
+	}
+	r.notifySubscribers(event)
+}
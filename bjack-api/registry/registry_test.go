@@ -0,0 +1,799 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/GRO4T/bjack-api/store"
+)
+
+func TestCreateAndGet(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+
+	// Act
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tables.Get(table.Id)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != table {
+		t.Error("Expected to get the created table")
+	}
+}
+
+func TestCreateWithRules(t *testing.T) {
+	// Arrange
+	tables := registry.New(registry.WithRules(blackjack.Rules{MaxPlayers: 1, InitialChips: 500}))
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = table.Join(context.Background(), "Player 2")
+
+	// Assert
+	if player.Chips != 500 {
+		t.Errorf("Expected 500 chips; got %v", player.Chips)
+	}
+	if !errors.Is(err, blackjack.ErrGameIsFull) {
+		t.Errorf("Expected %v; got %v", blackjack.ErrGameIsFull, err)
+	}
+}
+
+func TestGetMissingTable(t *testing.T) {
+	tables := registry.New()
+	if _, err := tables.Get("ABC234"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound; got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	tables.Remove(table.Id)
+
+	// Assert
+	if tables.Len() != 0 {
+		t.Errorf("Expected 0 tables; got %v", tables.Len())
+	}
+	if _, err := table.Join(context.Background(), "Player 1"); !errors.Is(err, registry.ErrTableClosed) {
+		t.Errorf("Expected ErrTableClosed; got %v", err)
+	}
+}
+
+func TestListenerNotifiedOnStateChange(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	events := []registry.Event{}
+	tables.AddListener(func(event registry.Event) {
+		events = append(events, event)
+	})
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	player, err := table.Join(context.Background(), "Player 1")
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected a single event; got %+v", events)
+	}
+	event := events[0]
+	if event.TableId != table.Id || event.Type != registry.PlayerJoined || event.PlayerId != player.Id {
+		t.Errorf("Expected PlayerJoined event for %v; got %+v", player.Id, event)
+	}
+	if event.Seq != 1 || event.Version != 1 {
+		t.Errorf("Expected seq 1 and version 1; got %v and %v", event.Seq, event.Version)
+	}
+	if event.Game == nil || len(event.Game.Players) != 1 {
+		t.Errorf("Expected the event to carry the game with 1 player; got %+v", event.Game)
+	}
+}
+
+func TestListenerNotNotifiedOnFailedCommand(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	events := []registry.Event{}
+	tables.AddListener(func(event registry.Event) {
+		events = append(events, event)
+	})
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err = table.Act(context.Background(), "ABCDEFGHJKLMNPQRSTUVWXYZ23", blackjack.Hit)
+
+	// Assert
+	if !errors.Is(err, blackjack.ErrGameNotInProgress) {
+		t.Errorf("Expected ErrGameNotInProgress; got %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no events; got %+v", events)
+	}
+}
+
+func TestCommandWithExpiredContext(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	_, err = table.Join(ctx, "Player 1")
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled; got %v", err)
+	}
+	_ = table.Do(context.Background(), func(game *blackjack.Blackjack) error {
+		if len(game.Players) != 0 {
+			t.Errorf("Expected 0 players; got %v", len(game.Players))
+		}
+		return nil
+	})
+}
+
+func TestTurnTimeout(t *testing.T) {
+	// Arrange
+	tables := registry.New(registry.WithTurnTimeout(time.Millisecond))
+	t.Cleanup(tables.Close)
+	timedOut := make(chan registry.Event, 1)
+	tables.AddListener(func(event registry.Event) {
+		if event.Type == registry.TurnTimedOut {
+			timedOut <- event
+		}
+	})
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	player, err := table.Join(ctx, "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.ToggleReady(ctx, player.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	var event registry.Event
+	select {
+	case event = <-timedOut:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected turn to time out")
+	}
+
+	// Assert
+	if event.PlayerId != player.Id {
+		t.Errorf("Expected turn of %v to time out; got %v", player.Id, event.PlayerId)
+	}
+	_ = table.Do(ctx, func(game *blackjack.Blackjack) error {
+		if game.State != blackjack.Finished {
+			t.Error("Expected game to be in Finished state")
+		}
+		return nil
+	})
+}
+
+func TestCommandWithExpectedVersion(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := table.Version()
+	if _, err := table.Join(context.Background(), "Player 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, staleErr := table.Join(registry.WithExpectedVersion(context.Background(), version), "Player 2")
+	_, currentErr := table.Join(registry.WithExpectedVersion(context.Background(), table.Version()), "Player 2")
+
+	// Assert
+	if !errors.Is(staleErr, registry.ErrVersionMismatch) {
+		t.Errorf("Expected %v; got %v", registry.ErrVersionMismatch, staleErr)
+	}
+	if currentErr != nil {
+		t.Errorf("Expected the command to succeed; got %v", currentErr)
+	}
+	_ = table.Do(context.Background(), func(game *blackjack.Blackjack) error {
+		if len(game.Players) != 2 {
+			t.Errorf("Expected 2 players; got %v", len(game.Players))
+		}
+		return nil
+	})
+}
+
+func TestParallelCreate(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	var wg sync.WaitGroup
+
+	// Act
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tables.Create(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	if tables.Len() != 100 {
+		t.Errorf("Expected 100 tables; got %v", tables.Len())
+	}
+}
+
+func TestParallelPlayerActions(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	player, err := table.Join(ctx, "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.ToggleReady(ctx, player.Id); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	successes := 0
+	var successesMu sync.Mutex
+
+	// Act
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := table.Act(ctx, player.Id, blackjack.Hit); err == nil {
+				successesMu.Lock()
+				successes++
+				successesMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	if successes != 1 {
+		t.Errorf("Expected exactly 1 successful hit; got %v", successes)
+	}
+	_ = table.Do(ctx, func(game *blackjack.Blackjack) error {
+		if len(game.GetPlayerHand(0)) != 3 {
+			t.Errorf("Expected 3 cards; got %v", len(game.GetPlayerHand(0)))
+		}
+		return nil
+	})
+}
+
+func TestRestoreFromStore(t *testing.T) {
+	// Arrange
+	s := store.NewMemoryStore()
+	tables := registry.New(registry.WithStore(s))
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables.Close()
+
+	// Act
+	restored := registry.New(registry.WithStore(s))
+	t.Cleanup(restored.Close)
+	err = restored.Restore()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	restoredTable, err := restored.Get(table.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = restoredTable.Do(context.Background(), func(game *blackjack.Blackjack) error {
+		if len(game.Players) != 1 || game.Players[0].Id != player.Id {
+			t.Errorf("Expected player %v to be restored", player.Id)
+		}
+		return nil
+	})
+	if restoredTable.Version() != table.Version() {
+		t.Errorf("Expected version %v to be restored; got %v", table.Version(), restoredTable.Version())
+	}
+}
+
+func TestRemoveDeletesFromStore(t *testing.T) {
+	// Arrange
+	s := store.NewMemoryStore()
+	tables := registry.New(registry.WithStore(s))
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	tables.Remove(table.Id)
+
+	// Assert
+	games, err := s.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 0 {
+		t.Errorf("Expected 0 stored tables; got %v", len(games))
+	}
+}
+
+// blockingStore blocks the first save after block until it is released.
+type blockingStore struct {
+	*store.MemoryStore
+	saving  chan struct{}
+	release chan struct{}
+	once    sync.Once
+	blocked bool
+}
+
+func (s *blockingStore) Save(tableId string, game *blackjack.Blackjack, version uint64) error {
+	if s.blocked {
+		s.once.Do(func() {
+			close(s.saving)
+			<-s.release
+		})
+	}
+	return s.MemoryStore.Save(tableId, game, version) //nolint: wrapcheck
+}
+
+func TestRemoveWaitsForCommandInProgress(t *testing.T) {
+	// Arrange
+	s := &blockingStore{
+		MemoryStore: store.NewMemoryStore(),
+		saving:      make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	tables := registry.New(registry.WithStore(s))
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.blocked = true
+	joined := make(chan struct{})
+	go func() {
+		_, _ = table.Join(context.Background(), "Alice")
+		close(joined)
+	}()
+	<-s.saving
+
+	// Act
+	removed := make(chan struct{})
+	go func() {
+		tables.Remove(table.Id)
+		close(removed)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(s.release)
+	<-removed
+	<-joined
+
+	// Assert
+	games, err := s.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 0 {
+		t.Errorf("Expected 0 stored tables; got %v", len(games))
+	}
+}
+
+func TestCreateDoesNotBlockRegistryWhileSaving(t *testing.T) {
+	// Arrange
+	s := &blockingStore{
+		MemoryStore: store.NewMemoryStore(),
+		saving:      make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+	tables := registry.New(registry.WithStore(s))
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.blocked = true
+	created := make(chan struct{})
+	go func() {
+		_, _ = tables.Create()
+		close(created)
+	}()
+	<-s.saving
+	defer func() {
+		close(s.release)
+		<-created
+	}()
+
+	// Act
+	got := make(chan error, 1)
+	go func() {
+		_, err := tables.Get(table.Id)
+		got <- err
+	}()
+
+	// Assert
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Errorf("Expected no error; got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected Get not to wait for Create to save the table")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := tables.Subscribe(table.Id)
+
+	// Act
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsubscribe()
+
+	// Assert
+	event := <-events
+	if event.Type != registry.PlayerJoined || event.PlayerId != player.Id {
+		t.Errorf("Expected PlayerJoined event for %v; got %v", player.Id, event)
+	}
+	if _, ok := <-events; ok {
+		t.Error("Expected the channel to be closed after unsubscribing")
+	}
+	if tables.Subscribers(table.Id) != 0 {
+		t.Errorf("Expected 0 subscribers; got %v", tables.Subscribers(table.Id))
+	}
+}
+
+func TestSubscriptionClosedWhenTableRemoved(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := tables.Subscribe(table.Id)
+	defer unsubscribe()
+
+	// Act
+	tables.Remove(table.Id)
+
+	// Assert
+	event := <-events
+	if event.Type != registry.TableClosed {
+		t.Errorf("Expected TableClosed event; got %v", event)
+	}
+	if _, ok := <-events; ok {
+		t.Error("Expected the channel to be closed")
+	}
+}
+
+func TestWatchResumesWithMissedEvents(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Player 1", "Player 2", "Player 3"} {
+		if _, err := table.Join(context.Background(), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Act
+	catchUp, _, unsubscribe, err := tables.Watch(context.Background(), table, true, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	// Assert
+	if len(catchUp) != 2 || catchUp[0].Seq != 2 || catchUp[1].Seq != 3 {
+		t.Fatalf("Expected events 2 and 3; got %+v", catchUp)
+	}
+	if len(catchUp[1].Game.Players) != 3 {
+		t.Errorf("Expected 3 players; got %v", len(catchUp[1].Game.Players))
+	}
+}
+
+func TestWatchSnapshotWhenMissedEventsAreGone(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.Join(context.Background(), "Player 2"); err != nil {
+		t.Fatal(err)
+	}
+	for range 100 {
+		if _, err := table.ToggleReady(context.Background(), player.Id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Act
+	catchUp, _, unsubscribe, err := tables.Watch(context.Background(), table, true, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	// Assert
+	if len(catchUp) != 1 || catchUp[0].Type != registry.Snapshot || catchUp[0].Seq != 102 {
+		t.Errorf("Expected a snapshot at seq 102; got %+v", catchUp)
+	}
+}
+
+func TestWatchSnapshotWhenNotResuming(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.Join(context.Background(), "Player 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	catchUp, events, unsubscribe, err := tables.Watch(context.Background(), table, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	if _, err := table.Join(context.Background(), "Player 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if len(catchUp) != 1 || catchUp[0].Type != registry.Snapshot || len(catchUp[0].Game.Players) != 1 {
+		t.Errorf("Expected a snapshot with 1 player; got %+v", catchUp)
+	}
+	if event := <-events; event.Seq != catchUp[0].Seq+1 {
+		t.Errorf("Expected the event following the snapshot; got %+v", event)
+	}
+}
+
+func TestCreateWhileDraining(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	tables.Drain()
+
+	// Act
+	_, err := tables.Create()
+
+	// Assert
+	if !errors.Is(err, registry.ErrShuttingDown) {
+		t.Errorf("Expected %v; got %v", registry.ErrShuttingDown, err)
+	}
+}
+
+func TestDealWhileDraining(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	first, err := table.Join(ctx, "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := table.Join(ctx, "Player 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables.Drain()
+
+	// Act
+	_, notLast := table.ToggleReady(ctx, first.Id)
+	_, toggled := table.ToggleReady(ctx, second.Id)
+	_, set := table.SetReady(ctx, second.Id, true)
+
+	// Assert
+	if notLast != nil {
+		t.Errorf("Expected a player to get ready while others are not; got %v", notLast)
+	}
+	for _, err := range []error{toggled, set} {
+		if !errors.Is(err, registry.ErrShuttingDown) {
+			t.Errorf("Expected %v; got %v", registry.ErrShuttingDown, err)
+		}
+	}
+	if err := tables.WaitForRounds(ctx); err != nil {
+		t.Errorf("Expected no rounds in progress; got %v", err)
+	}
+}
+
+func TestWaitForRounds(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	game := blackjack.New(nil)
+	if _, err := game.AddPlayer("Player 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	table := tables.Put("ABC234", &game)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// Act
+	inProgress := tables.WaitForRounds(ctx)
+	_ = table.Do(context.Background(), func(game *blackjack.Blackjack) error {
+		game.State = blackjack.WaitingForPlayers
+		return nil
+	})
+	finished := tables.WaitForRounds(context.Background())
+
+	// Assert
+	if !errors.Is(inProgress, context.DeadlineExceeded) {
+		t.Errorf("Expected the round in progress to be waited for until the deadline; got %v", inProgress)
+	}
+	if finished != nil {
+		t.Errorf("Expected no rounds in progress; got %v", finished)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	// Arrange
+	s := store.NewMemoryStore()
+	tables := registry.New(registry.WithStore(s))
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.Join(context.Background(), "Player 1"); err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := tables.Subscribe(table.Id)
+	defer unsubscribe()
+
+	// Act
+	err = tables.Shutdown(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := <-events
+	if event.Type != registry.ServerShutdown {
+		t.Errorf("Expected ServerShutdown event; got %v", event)
+	}
+	if _, ok := <-events; ok {
+		t.Error("Expected the channel to be closed")
+	}
+	if _, err := table.Join(context.Background(), "Player 2"); !errors.Is(err, registry.ErrTableClosed) {
+		t.Errorf("Expected %v; got %v", registry.ErrTableClosed, err)
+	}
+	games, err := s.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record, ok := games[table.Id]; !ok || len(record.Game.Players) != 1 {
+		t.Errorf("Expected the table to be kept in the store; got %v", games)
+	}
+}
+
+func TestReady(t *testing.T) {
+	// Arrange
+	tables := registry.New(registry.WithStore(store.NewMemoryStore()))
+	t.Cleanup(tables.Close)
+	ready := tables.Ready()
+
+	// Act
+	tables.Drain()
+
+	// Assert
+	if ready != nil {
+		t.Errorf("Expected the registry to be ready; got %v", ready)
+	}
+	if err := tables.Ready(); !errors.Is(err, registry.ErrShuttingDown) {
+		t.Errorf("Expected %v; got %v", registry.ErrShuttingDown, err)
+	}
+}
+
+func TestCommandPanicFailsWithInternalError(t *testing.T) {
+	// Arrange
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	table, err := tables.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err = table.Do(context.Background(), func(game *blackjack.Blackjack) error {
+		_ = game.Deck[len(game.Deck)]
+		return nil
+	})
+
+	// Assert
+	if !errors.Is(err, registry.ErrInternal) {
+		t.Errorf("Expected %v; got %v", registry.ErrInternal, err)
+	}
+	if _, err := table.Join(context.Background(), "Player 1"); err != nil {
+		t.Errorf("Expected the table to keep serving commands; got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+)
+
+const (
+	roundPollInterval = 100 * time.Millisecond
+)
+
+var (
+	ErrShuttingDown     = blackjack.NewError(blackjack.CodeTableUnavailable, "server is shutting down")
+	ErrStoreUnavailable = blackjack.NewError(blackjack.CodeTableUnavailable, "store unavailable")
+)
+
+// Drain makes Create fail with ErrShuttingDown. Existing tables keep going,
+// but the last player getting ready fails with ErrShuttingDown instead of dealing a new round.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Draining reports whether Drain was called.
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Ready reports whether new tables can be served: the registry is not draining
+// and the store, if any, can be used.
+func (r *Registry) Ready() error {
+	if r.Draining() {
+		return ErrShuttingDown
+	}
+	if r.store == nil {
+		return nil
+	}
+	if err := r.store.Ping(); err != nil {
+		slog.Warn(err.Error())
+		return ErrStoreUnavailable
+	}
+	return nil
+}
+
+// WaitForRounds waits until no table has cards dealt, so that players can finish their hands.
+// It returns an error if rounds are still in progress once ctx is done.
+func (r *Registry) WaitForRounds(ctx context.Context) error {
+	ticker := time.NewTicker(roundPollInterval)
+	defer ticker.Stop()
+	for {
+		n := r.roundsInProgress(ctx)
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d rounds still in progress: %w", n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Registry) roundsInProgress(ctx context.Context) int {
+	n := 0
+	for _, table := range r.All() {
+		inProgress := false
+		err := table.Do(ctx, func(game *blackjack.Blackjack) error {
+			inProgress = game.State == blackjack.CardsDealt
+			return nil
+		})
+		if err == nil && inProgress || err != nil && !errors.Is(err, ErrTableClosed) {
+			n++
+		}
+	}
+	return n
+}
+
+// Shutdown drains the registry, saves and stops every table and then tells its subscribers
+// that the server is going away with a ServerShutdown event. Tables stay in the store,
+// so that they are restored by the next server.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.Drain()
+	var errs []error
+	for _, table := range r.All() {
+		if err := table.Stop(ctx); err != nil && !errors.Is(err, ErrTableClosed) {
+			errs = append(errs, fmt.Errorf("table %v: %w", table.Id, err))
+		}
+		r.notify(Event{
+			TableId: table.Id,
+			Type:    ServerShutdown,
+			Seq:     table.seq.Add(1),
+			Version: table.Version(),
+		})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to stop tables: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"context"
+	"log/slog"
+)
+
+const (
+	subscriptionBuffer = 64
+	// Number of recent events kept per table for subscribers that resume.
+	historyLen = 64
+)
+
+// history holds the most recent events of a table.
+type history struct {
+	events  []Event
+	lastSeq uint64
+}
+
+// since returns the events published after seq. ok is false
+// if some of them are no longer kept or seq is from the future.
+func (h *history) since(seq uint64) (events []Event, ok bool) {
+	if h == nil {
+		return nil, seq == 0
+	}
+	if seq > h.lastSeq {
+		return nil, false
+	}
+	if seq == h.lastSeq {
+		return nil, true
+	}
+	if len(h.events) == 0 || h.events[0].Seq > seq+1 {
+		return nil, false
+	}
+	for i, event := range h.events {
+		if event.Seq > seq {
+			return append([]Event{}, h.events[i:]...), true
+		}
+	}
+	return nil, true
+}
+
+type subscription struct {
+	events chan Event
+}
+
+// Subscribe returns a channel that receives the events of a single table.
+// The channel is closed when the table is removed, when unsubscribe is called
+// or when the subscriber falls more than subscriptionBuffer events behind.
+func (r *Registry) Subscribe(tableId string) (events <-chan Event, unsubscribe func()) {
+	sub := &subscription{events: make(chan Event, subscriptionBuffer)}
+	r.subsMu.Lock()
+	r.addSubscription(tableId, sub)
+	r.subsMu.Unlock()
+
+	return sub.events, func() {
+		r.subsMu.Lock()
+		defer r.subsMu.Unlock()
+		r.dropSubscription(tableId, sub)
+	}
+}
+
+// Watch subscribes to the table and returns what the subscriber needs to catch up first:
+// the events published after sinceSeq when resuming, otherwise or if these are no longer kept,
+// a snapshot. Events received afterwards with a Seq not above the last caught up one are duplicates.
+func (r *Registry) Watch(
+	ctx context.Context,
+	table *Table,
+	resume bool,
+	sinceSeq uint64,
+) (catchUp []Event, events <-chan Event, unsubscribe func(), err error) {
+	sub := &subscription{events: make(chan Event, subscriptionBuffer)}
+	r.subsMu.Lock()
+	missed, ok := r.history[table.Id].since(sinceSeq)
+	r.addSubscription(table.Id, sub)
+	r.subsMu.Unlock()
+	unsubscribe = func() {
+		r.subsMu.Lock()
+		defer r.subsMu.Unlock()
+		r.dropSubscription(table.Id, sub)
+	}
+
+	if resume && ok {
+		return missed, sub.events, unsubscribe, nil
+	}
+	snapshot, err := table.Snapshot(ctx)
+	if err != nil {
+		unsubscribe()
+		return nil, nil, nil, err
+	}
+	return []Event{snapshot}, sub.events, unsubscribe, nil
+}
+
+// Subscribers returns the number of active subscriptions to the table.
+func (r *Registry) Subscribers(tableId string) int {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	return len(r.subscribers[tableId])
+}
+
+// AllSubscribers returns the number of active subscriptions to all tables.
+func (r *Registry) AllSubscribers() int {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	n := 0
+	for _, subs := range r.subscribers {
+		n += len(subs)
+	}
+	return n
+}
+
+func (r *Registry) notifySubscribers(event Event) {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+	r.record(event)
+	for sub := range r.subscribers[event.TableId] {
+		select {
+		case sub.events <- event:
+		default:
+			slog.Warn("Dropping slow subscriber", "tableId", event.TableId)
+			r.dropSubscription(event.TableId, sub)
+		}
+	}
+	if event.Type == TableClosed || event.Type == ServerShutdown {
+		for sub := range r.subscribers[event.TableId] {
+			r.dropSubscription(event.TableId, sub)
+		}
+	}
+}
+
+// record must be called with subsMu held.
+func (r *Registry) record(event Event) {
+	switch event.Type {
+	case TableClosed:
+		delete(r.history, event.TableId)
+		return
+	case ServerShutdown:
+		return
+	}
+	h, ok := r.history[event.TableId]
+	if !ok {
+		h = &history{}
+		r.history[event.TableId] = h
+	}
+	h.events = append(h.events, event)
+	if len(h.events) > historyLen {
+		h.events = h.events[1:]
+	}
+	h.lastSeq = event.Seq
+}
+
+// addSubscription must be called with subsMu held.
+func (r *Registry) addSubscription(tableId string, sub *subscription) {
+	if r.subscribers[tableId] == nil {
+		r.subscribers[tableId] = map[*subscription]struct{}{}
+	}
+	r.subscribers[tableId][sub] = struct{}{}
+}
+
+// dropSubscription must be called with subsMu held.
+func (r *Registry) dropSubscription(tableId string, sub *subscription) {
+	subs, ok := r.subscribers[tableId]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	close(sub.events)
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(r.subscribers, tableId)
+	}
+}
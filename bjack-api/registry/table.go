@@ -0,0 +1,418 @@
+// nolint: wrapcheck
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/requestid"
+	"github.com/GRO4T/bjack-api/store"
+)
+
+var (
+	ErrTableClosed     = blackjack.NewError(blackjack.CodeTableUnavailable, "table closed")
+	ErrVersionMismatch = blackjack.NewError(blackjack.CodeVersionMismatch, "table version does not match")
+	ErrInternal        = blackjack.NewError(blackjack.CodeInternal, "internal error")
+)
+
+const (
+	tickInterval = time.Second
+)
+
+type EventType string
+
+const (
+	PlayerJoined       EventType = "PlayerJoined"
+	PlayerLeft         EventType = "PlayerLeft"
+	PlayerReadyToggled EventType = "PlayerReadyToggled"
+	BetPlaced          EventType = "BetPlaced"
+	PlayerActed        EventType = "PlayerActed"
+	TurnTimedOut       EventType = "TurnTimedOut"
+	TableClosed        EventType = "TableClosed"
+	// ServerShutdown is published to subscribers when the server is going away.
+	// The table is kept and can be watched again once the server is back.
+	ServerShutdown EventType = "ServerShutdown"
+	// Snapshot is not published, it marks the current state returned by Table.Snapshot.
+	Snapshot EventType = "Snapshot"
+)
+
+// Event is published after a command changes the state of a table.
+type Event struct {
+	TableId  string    `json:"tableId"`
+	Type     EventType `json:"type"`
+	PlayerId string    `json:"playerId,omitempty"`
+	// Seq numbers the events of a table, so subscribers can tell which ones they have seen.
+	Seq uint64 `json:"seq"`
+	// Version of the table after the event. It grows with every change of the game.
+	Version uint64 `json:"version"`
+	// Game is a copy of the game right after the event. Nil for TableClosed and ServerShutdown.
+	Game *blackjack.Blackjack `json:"-"`
+}
+
+type expectedVersionKey struct{}
+
+// WithExpectedVersion returns a context that makes commands fail with ErrVersionMismatch
+// unless the table is at the given version when they are executed.
+func WithExpectedVersion(ctx context.Context, version uint64) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+type command struct {
+	ctx     context.Context //nolint: containedctx
+	execute func(game *blackjack.Blackjack) (*Event, error)
+	done    chan error
+}
+
+// Table owns a single game. The game is only accessed from the table's
+// goroutine, which processes commands one at a time in the order they were sent.
+type Table struct {
+	Id           string
+	game         *blackjack.Blackjack
+	commands     chan command
+	closed       chan struct{}
+	closeOnce    sync.Once
+	done         chan struct{} // closed once the table goroutine has exited
+	publish      func(Event)
+	draining     func() bool
+	turnTimeout  time.Duration
+	lastActivity atomic.Int64 // Unix nanoseconds
+	store        store.Store
+	seq          atomic.Uint64
+	version      atomic.Uint64
+	players      atomic.Int64
+}
+
+func newTable(
+	id string,
+	game *blackjack.Blackjack,
+	version uint64,
+	publish func(Event),
+	draining func() bool,
+	turnTimeout time.Duration,
+	s store.Store,
+) *Table {
+	t := &Table{
+		Id:          id,
+		game:        game,
+		commands:    make(chan command),
+		closed:      make(chan struct{}),
+		done:        make(chan struct{}),
+		publish:     publish,
+		draining:    draining,
+		turnTimeout: turnTimeout,
+		store:       s,
+	}
+	t.lastActivity.Store(time.Now().UnixNano())
+	t.version.Store(version)
+	t.players.Store(int64(len(game.Players)))
+	go t.run()
+	return t
+}
+
+func (t *Table) Join(ctx context.Context, name string) (blackjack.Player, error) {
+	var player blackjack.Player
+	err := t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		p, err := game.AddPlayer(name)
+		if err != nil {
+			return nil, err
+		}
+		player = *p
+		return &Event{Type: PlayerJoined, PlayerId: p.Id}, nil
+	})
+	return player, err
+}
+
+func (t *Table) Leave(ctx context.Context, playerId string) error {
+	return t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		if err := game.RemovePlayer(playerId); err != nil {
+			return nil, err
+		}
+		return &Event{Type: PlayerLeft, PlayerId: playerId}, nil
+	})
+}
+
+func (t *Table) ToggleReady(ctx context.Context, playerId string) (blackjack.Player, error) {
+	var player blackjack.Player
+	err := t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		if err := t.checkDeal(game, playerId); err != nil {
+			return nil, err
+		}
+		p, err := game.TogglePlayerReady(playerId)
+		if err != nil {
+			return nil, err
+		}
+		player = *p
+		return &Event{Type: PlayerReadyToggled, PlayerId: playerId}, nil
+	})
+	return player, err
+}
+
+// SetReady makes the player ready or not ready. No event is published if the player already is.
+func (t *Table) SetReady(ctx context.Context, playerId string, ready bool) (blackjack.Player, error) {
+	var player blackjack.Player
+	err := t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		wasReady := slices.ContainsFunc(game.Players, func(p *blackjack.Player) bool {
+			return p.Id == playerId && p.IsReady
+		})
+		if ready {
+			if err := t.checkDeal(game, playerId); err != nil {
+				return nil, err
+			}
+		}
+		p, err := game.SetPlayerReady(playerId, ready)
+		if err != nil {
+			return nil, err
+		}
+		player = *p
+		if wasReady == ready {
+			return nil, nil //nolint: nilnil
+		}
+		return &Event{Type: PlayerReadyToggled, PlayerId: playerId}, nil
+	})
+	return player, err
+}
+
+// checkDeal fails with ErrShuttingDown if the player getting ready would deal a new round
+// while the server is draining, so that shutdown only waits for the rounds in progress.
+func (t *Table) checkDeal(game *blackjack.Blackjack, playerId string) error {
+	if !t.draining() || game.State != blackjack.WaitingForPlayers {
+		return nil
+	}
+	gettingReady := false
+	for _, p := range game.Players {
+		if p.Id == playerId {
+			gettingReady = !p.IsReady
+		} else if !p.IsReady {
+			return nil
+		}
+	}
+	if !gettingReady {
+		return nil
+	}
+	return ErrShuttingDown
+}
+
+func (t *Table) PlaceBet(ctx context.Context, playerId string, amount int) (blackjack.Player, error) {
+	var player blackjack.Player
+	err := t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		p, err := game.PlaceBet(playerId, amount)
+		if err != nil {
+			return nil, err
+		}
+		player = *p
+		return &Event{Type: BetPlaced, PlayerId: playerId}, nil
+	})
+	return player, err
+}
+
+func (t *Table) Act(ctx context.Context, playerId string, action blackjack.Action) error {
+	return t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		if err := game.PlayerAction(playerId, action); err != nil {
+			return nil, err
+		}
+		return &Event{Type: PlayerActed, PlayerId: playerId}, nil
+	})
+}
+
+// Do runs f on the table goroutine. No event is published afterwards,
+// so f is meant for reading the game state. Do returns early once ctx is done,
+// while f may still be running, so the results of f must only be used if Do returns nil.
+func (t *Table) Do(ctx context.Context, f func(game *blackjack.Blackjack) error) error {
+	return t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		return nil, f(game)
+	})
+}
+
+// Snapshot returns a copy of the game together with the sequence number
+// of the last published event and the current version.
+func (t *Table) Snapshot(ctx context.Context) (Event, error) {
+	var snapshot Event
+	err := t.send(ctx, func(game *blackjack.Blackjack) (*Event, error) {
+		snapshot = Event{
+			TableId: t.Id,
+			Type:    Snapshot,
+			Seq:     t.seq.Load(),
+			Version: t.version.Load(),
+			Game:    game.Clone(),
+		}
+		return nil, nil //nolint: nilnil
+	})
+	return snapshot, err
+}
+
+// Version returns the number of changes made to the game since the table was started.
+func (t *Table) Version() uint64 {
+	return t.version.Load()
+}
+
+// Players returns the number of players seated at the table.
+func (t *Table) Players() int {
+	return int(t.players.Load())
+}
+
+// LastActivity returns when the table was created or last changed by a command.
+func (t *Table) LastActivity() time.Time {
+	return time.Unix(0, t.lastActivity.Load())
+}
+
+// Stop saves the game and stops the table goroutine, so that no command changes the game
+// after it was saved. Commands sent afterwards fail with ErrTableClosed.
+func (t *Table) Stop(ctx context.Context) error {
+	return t.send(ctx, func(*blackjack.Blackjack) (*Event, error) {
+		t.save()
+		t.Close()
+		return nil, nil //nolint: nilnil
+	})
+}
+
+// closeIfIdle closes the table if no command changed it for at least ttl at the given time.
+// The check and closing are done in one command, so a command sent meanwhile either
+// runs before and keeps the table open, or fails with ErrTableClosed.
+func (t *Table) closeIfIdle(ctx context.Context, now time.Time, ttl time.Duration) (bool, error) {
+	idle := false
+	err := t.send(ctx, func(*blackjack.Blackjack) (*Event, error) {
+		if now.Sub(t.LastActivity()) < ttl {
+			return nil, nil //nolint: nilnil
+		}
+		idle = true
+		t.Close()
+		return nil, nil //nolint: nilnil
+	})
+	return idle, err
+}
+
+// Close stops the table goroutine. Commands sent afterwards fail with ErrTableClosed.
+// Close does not wait for a command in progress to finish, see Wait.
+func (t *Table) Close() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+}
+
+// Wait blocks until the table goroutine has exited after Close, so that the command
+// in progress, if any, is done and the game is not saved anymore.
+// It must not be called from a command.
+func (t *Table) Wait() {
+	<-t.done
+}
+
+func (t *Table) send(ctx context.Context, execute func(game *blackjack.Blackjack) (*Event, error)) error {
+	cmd := command{ctx: ctx, execute: execute, done: make(chan error, 1)}
+	select {
+	case t.commands <- cmd:
+	case <-t.closed:
+		return ErrTableClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-cmd.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Table) run() {
+	defer close(t.done)
+	var ticks <-chan time.Time
+	if t.turnTimeout > 0 {
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+	for {
+		select {
+		case cmd := <-t.commands:
+			t.execute(cmd)
+		case now := <-ticks:
+			t.tick(now)
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+func (t *Table) execute(cmd command) {
+	// The sender may have given up while the command was queued.
+	if err := cmd.ctx.Err(); err != nil {
+		cmd.done <- err
+		return
+	}
+	// The table may have been closed while the command was queued.
+	select {
+	case <-t.closed:
+		cmd.done <- ErrTableClosed
+		return
+	default:
+	}
+	if expected, ok := cmd.ctx.Value(expectedVersionKey{}).(uint64); ok && expected != t.Version() {
+		cmd.done <- blackjack.NewError(blackjack.CodeVersionMismatch,
+			"table is at version %d, not %d", t.Version(), expected)
+		return
+	}
+	event, err := t.recovered(cmd.ctx, func() (*Event, error) {
+		return cmd.execute(t.game)
+	})
+	if event != nil {
+		t.emit(*event, time.Now())
+	}
+	cmd.done <- err
+}
+
+// tick makes the current player stand if they did not act within the turn timeout.
+func (t *Table) tick(now time.Time) {
+	if t.game.State != blackjack.CardsDealt || now.Sub(t.LastActivity()) < t.turnTimeout {
+		return
+	}
+	player := t.game.Players[t.game.CurrentPlayer-1]
+	_, err := t.recovered(context.Background(), func() (*Event, error) {
+		return nil, t.game.PlayerAction(player.Id, blackjack.Stand)
+	})
+	if err != nil {
+		return
+	}
+	t.emit(Event{Type: TurnTimedOut, PlayerId: player.Id}, now)
+}
+
+// recovered runs f, turning a panic into ErrInternal, so that a bug in the game fails
+// the command instead of taking down the server. The game is neither saved nor published,
+// as it may have been left half way through the change.
+func (t *Table) recovered(ctx context.Context, f func() (*Event, error)) (event *Event, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Error(fmt.Sprintf("Panic in table %v: %v", t.Id, p),
+				"tableId", t.Id, "requestId", requestid.FromContext(ctx), "stack", string(debug.Stack()))
+			event, err = nil, ErrInternal
+		}
+	}()
+	return f()
+}
+
+// emit saves the game after a change and publishes the event.
+func (t *Table) emit(event Event, now time.Time) {
+	t.lastActivity.Store(now.UnixNano())
+	event.TableId = t.Id
+	event.Version = t.version.Add(1)
+	event.Seq = t.seq.Add(1)
+	event.Game = t.game.Clone()
+	t.players.Store(int64(len(t.game.Players)))
+	t.save()
+	t.publish(event)
+}
+
+func (t *Table) save() {
+	if t.store == nil {
+		return
+	}
+	if err := t.store.Save(t.Id, t.game, t.Version()); err != nil {
+		slog.Error(fmt.Sprintf("Failed to save table %v: %v", t.Id, err))
+	}
+}
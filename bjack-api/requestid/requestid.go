@@ -0,0 +1,67 @@
+// Package requestid identifies requests across the logs of the REST and gRPC servers
+// and the tables that serve them.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// Header is the HTTP header carrying the id of a request, set by a proxy or the client.
+	// The server answers with the id it used.
+	Header = "X-Request-Id"
+	// Metadata is the gRPC metadata key carrying the id of a call.
+	Metadata = "x-request-id"
+
+	maxLength = 128
+	idBytes   = 16
+)
+
+type contextKey struct{}
+
+// New returns a random 128-bit id.
+func New() string {
+	b := make([]byte, idBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an id received from outside can be used, e.g. in logs.
+// Ids are limited to letters, digits and the punctuation of common id formats.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Ensure returns id if it is valid and a new id otherwise.
+func Ensure(id string) string {
+	if Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// NewContext returns a context carrying the request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id of the context, or an empty string if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,60 @@
+package requestid_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/requestid"
+)
+
+func TestValid(t *testing.T) {
+	for _, tc := range []struct {
+		id    string
+		valid bool
+	}{
+		{"0f8fad5b-d9cb-469f-a165-70867728950e", true},
+		{"req_42.retry:1", true},
+		{"", false},
+		{strings.Repeat("a", 129), false},
+		{"id with spaces", false},
+		{"id\nforged log line", false},
+	} {
+		t.Run(tc.id, func(t *testing.T) {
+			// Act
+			valid := requestid.Valid(tc.id)
+
+			// Assert
+			if valid != tc.valid {
+				t.Errorf("Expected %v; got %v", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	// Act
+	kept := requestid.Ensure("abc-123")
+	generated := requestid.Ensure("not valid")
+
+	// Assert
+	if kept != "abc-123" {
+		t.Errorf("Expected the valid id to be kept; got %v", kept)
+	}
+	if !requestid.Valid(generated) || generated == requestid.Ensure("not valid") {
+		t.Errorf("Expected a new random id; got %v", generated)
+	}
+}
+
+func TestContext(t *testing.T) {
+	// Act
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+
+	// Assert
+	if id := requestid.FromContext(ctx); id != "abc-123" {
+		t.Errorf("Expected abc-123; got %v", id)
+	}
+	if id := requestid.FromContext(context.Background()); id != "" {
+		t.Errorf("Expected no id; got %v", id)
+	}
+}
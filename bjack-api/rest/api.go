@@ -1,23 +1,44 @@
 package rest
 
 import (
-	"crypto/rand"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/big"
 	"net/http"
-	"strconv"
+	"net/url"
+	"strings"
+	"time"
 
 	"log/slog"
 
+	"github.com/GRO4T/bjack-api/auth"
 	"github.com/GRO4T/bjack-api/blackjack"
 	"github.com/GRO4T/bjack-api/constant"
-	"github.com/gorilla/websocket"
+	"github.com/GRO4T/bjack-api/ids"
+	"github.com/GRO4T/bjack-api/registry"
 )
 
 type RestApi struct {
-	Games      map[string]*blackjack.Blackjack
-	Websockets map[string][]*websocket.Conn // TODO: Test if the websockets will close automatically when the server is killed.
+	Signer       *auth.Signer
+	Tables       *registry.Registry
+	hub          *hub
+	pingInterval time.Duration
+	metrics      http.Handler
+}
+
+// WithPingInterval changes how often websockets are pinged.
+func WithPingInterval(d time.Duration) func(*RestApi) {
+	return func(a *RestApi) {
+		a.pingInterval = d
+	}
+}
+
+// WithMetrics serves the metrics exposed by h at /metrics.
+func WithMetrics(h http.Handler) func(*RestApi) {
+	return func(a *RestApi) {
+		a.metrics = h
+	}
 }
 
 type CreateGameRequest struct {
@@ -34,232 +55,454 @@ type AddPlayerRequest struct {
 
 type AddPlayerResponse struct {
 	PlayerId string `json:"playerId"`
+	Token    string `json:"token"`
+}
+
+type SetPlayerReadyRequest struct {
+	Ready bool `json:"ready"`
+}
+
+type PlayerActionRequest struct {
+	Action string `json:"action"`
 }
 
-func NewApi() RestApi {
-	return RestApi{
-		Games:      map[string]*blackjack.Blackjack{},
-		Websockets: map[string][]*websocket.Conn{},
+func NewApi(signer *auth.Signer, tables *registry.Registry, options ...func(*RestApi)) *RestApi {
+	a := &RestApi{
+		Signer:       signer,
+		Tables:       tables,
+		hub:          newHub(),
+		pingInterval: DefaultPingInterval,
+	}
+	for _, o := range options {
+		o(a)
 	}
+	return a
 }
 
-func (a *RestApi) CreateGame(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// Websockets returns the number of open websockets of the table.
+func (a *RestApi) Websockets(tableId string) int {
+	return a.hub.count(tableId)
+}
+
+// OpenWebsockets returns the number of open websockets of all tables.
+func (a *RestApi) OpenWebsockets() int {
+	return a.hub.total()
+}
+
+// WaitForWebsockets waits until all websockets are closed. http.Server.Shutdown
+// does not wait for them, as they are hijacked connections.
+func (a *RestApi) WaitForWebsockets(ctx context.Context) error {
+	ticker := time.NewTicker(websocketPollInterval)
+	defer ticker.Stop()
+	for a.OpenWebsockets() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d websockets still open: %w", a.OpenWebsockets(), ctx.Err())
+		case <-ticker.C:
+		}
 	}
+	return nil
+}
 
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// routes lists the API endpoints. Every route must be described by Spec.
+func (a *RestApi) routes() []route {
+	return []route{
+		{"POST /v1/tables", a.CreateGame},
+		{"GET /v1/tables/{tableId}", a.GetGameState},
+		{"POST /v1/tables/{tableId}/players", a.AddPlayer},
+		{"DELETE /v1/tables/{tableId}/players/{playerId}", a.RemovePlayer},
+		{"PUT /v1/tables/{tableId}/players/{playerId}/ready", a.SetPlayerReady},
+		{"POST /v1/tables/{tableId}/players/{playerId}/actions", a.PlayerAction},
+		{"GET /v1/tables/{tableId}/events", a.StreamEvents},
+		{"GET /v1/tables/{tableId}/updates", a.AddStateObserver},
+
+		// Operational endpoints live at the paths tooling expects and are not versioned.
+		{"GET /healthz", a.Healthz},
+		{"GET /readyz", a.Readyz},
+		{"GET /metrics", a.ServeMetrics},
+
+		// Unversioned routes are deprecated and will be removed in the next release.
+		{"POST /tables", deprecated("/v1/tables", a.CreateGame)},
+		{"GET /tables/{tableId}", deprecated("/v1/tables/{tableId}", a.GetGameState)},
+		{"POST /tables/players/{tableId}", deprecated("/v1/tables/{tableId}/players", a.AddPlayer)},
+		{"DELETE /tables/players/{tableId}/{playerId}",
+			deprecated("/v1/tables/{tableId}/players/{playerId}", a.RemovePlayer)},
+		{"POST /tables/ready/{tableId}/{playerId}",
+			deprecated("/v1/tables/{tableId}/players/{playerId}/ready", a.TogglePlayerReady)},
+		{"POST /tables/{tableId}/{playerId}",
+			deprecated("/v1/tables/{tableId}/players/{playerId}/actions", a.legacyPlayerAction)},
+		{"GET /tables/{tableId}/events", deprecated("/v1/tables/{tableId}/events", a.StreamEvents)},
+		{"GET /state-updates/{tableId}", deprecated("/v1/tables/{tableId}/updates", a.AddStateObserver)},
+	}
+}
+
+// deprecated marks responses of a deprecated route and links the route that replaces it.
+// Wildcards in successor are filled in with the values from the request.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := successor
+		for {
+			start := strings.Index(link, "{")
+			end := strings.Index(link, "}")
+			if start < 0 || end < start {
+				break
+			}
+			link = link[:start] + url.PathEscape(r.PathValue(link[start+1:end])) + link[end+1:]
+		}
+		w.Header().Set("Deprecation", "true")
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", link))
+		next(w, r)
+	}
+}
+
+// Handler routes requests to the API endpoints and serves the API documentation
+// at /openapi.json and /docs.
+func (a *RestApi) Handler() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, route := range a.routes() {
+		mux.HandleFunc(route.pattern, route.handler)
+	}
+	mux.HandleFunc("GET /openapi.json", a.ServeSpec)
+	mux.HandleFunc("GET /docs", a.ServeDocs)
+	mux.HandleFunc("GET /docs/{file}", a.ServeDocsAsset)
+	return mux
+}
+
+func (a *RestApi) CreateGame(w http.ResponseWriter, r *http.Request) {
 	var reqData CreateGameRequest
 	err := json.NewDecoder(r.Body).Decode(&reqData)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Failed to decode request: %v", err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, invalidArgument("Invalid request body: %v", err))
 		return
 	}
 
 	if reqData.PlayerName == "" {
-		http.Error(w, "Player name cannot be empty", http.StatusBadRequest)
+		writeError(w, r, invalidArgument("Player name cannot be empty"))
 		return
 	}
 
-	tableId := getRandomId()
-	newGame := blackjack.New(func() {
-		for _, ws := range a.Websockets[tableId] {
-			if err := ws.WriteMessage(websocket.TextMessage, []byte("NewState")); err != nil {
-				slog.Error(fmt.Sprintf("Failed to send data via websocket: %v", err))
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-				return
-			}
-		}
-	})
-	a.Games[tableId] = &newGame
+	table, err := a.Tables.Create()
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to create table: %w", err))
+		return
+	}
+	tableId := table.Id
 
 	var resp CreateGameResponse
 	resp.TableId = tableId
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		slog.Error(fmt.Sprintf("Failed to encode response: %v", err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("failed to encode response: %w", err))
 		return
 	}
 	slog.Debug("Created a new game", "tableId", tableId)
 }
 
 func (a *RestApi) GetGameState(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-
 	tableId := r.PathValue("tableId")
 
-	game, ok := a.Games[tableId]
-	if !ok {
-		http.Error(w, "Game not found", http.StatusNotFound)
+	if !validateIds(w, r, tableId) {
+		return
+	}
+
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(r.Context(), constant.CommandTimeout)
+	defer cancel()
+	var state []byte
+	var version uint64
+	err = table.Do(ctx, func(game *blackjack.Blackjack) error {
+		encoded, err := json.Marshal(game)
+		if err != nil {
+			return err
+		}
+		state, version = encoded, table.Version()
+		return nil
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(game); err != nil {
-		slog.Error(fmt.Sprintf("Failed to encode response: %v", err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	w.Header().Set("ETag", etag(version))
+	if _, err := w.Write(state); err != nil {
+		slog.Error(fmt.Sprintf("Failed to write response: %v", err))
 		return
 	}
 	slog.Debug("Retrieved game state", "tableId", tableId)
 }
 
 func (a *RestApi) AddPlayer(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-
 	tableId := r.PathValue("tableId")
 
-	game, ok := a.Games[tableId]
-	if !ok {
-		http.Error(w, "Game not found", http.StatusNotFound)
+	if !validateIds(w, r, tableId) {
+		return
+	}
+
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	var reqData AddPlayerRequest
-	err := json.NewDecoder(r.Body).Decode(&reqData)
+	err = json.NewDecoder(r.Body).Decode(&reqData)
 	if err != nil {
-		slog.Error(fmt.Sprintf("Failed to decode request: %v", err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, invalidArgument("Invalid request body: %v", err))
 		return
 	}
 
 	if reqData.PlayerName == "" {
-		http.Error(w, "Player name cannot be empty", http.StatusBadRequest)
+		writeError(w, r, invalidArgument("Player name cannot be empty"))
 		return
 	}
 
-	newPlayer, err := game.AddPlayer(reqData.PlayerName)
+	ctx, cancel, err := commandContext(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+	defer cancel()
+	newPlayer, err := table.Join(ctx, reqData.PlayerName)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	token, err := a.Signer.Issue(tableId, newPlayer.Id)
+	if err != nil {
+		writeError(w, r, fmt.Errorf("failed to issue session token: %w", err))
 		return
 	}
 
 	var resp AddPlayerResponse
 	resp.PlayerId = newPlayer.Id
+	resp.Token = token
 	w.Header().Set("Content-Type", "application/json")
+	// The token must not be kept by caches, nor replayed by Idempotent.
+	w.Header().Set("Cache-Control", "no-store")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		slog.Error(fmt.Sprintf("Failed to encode response: %v", err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("failed to encode response: %w", err))
 		return
 	}
 	slog.Debug("Added player to game", "playerId", newPlayer.Id, "tableId", tableId)
 }
 
 func (a *RestApi) RemovePlayer(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-
 	tableId := r.PathValue("tableId")
 	playerId := r.PathValue("playerId")
 
-	game, ok := a.Games[tableId]
-	if !ok {
-		http.Error(w, "Game not found", http.StatusNotFound)
+	if !validateIds(w, r, tableId, playerId) {
+		return
+	}
+	if !a.authorizePlayer(w, r, tableId, playerId) {
 		return
 	}
 
-	if err := game.RemovePlayer(playerId); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx, cancel, err := commandContext(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer cancel()
+	err = table.Leave(ctx, playerId)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	slog.Debug("Removed player from game", "playerId", playerId, "tableId", tableId)
 }
 
+// TogglePlayerReady serves the deprecated readiness route.
+//
+// Deprecated: Use SetPlayerReady.
 func (a *RestApi) TogglePlayerReady(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	tableId := r.PathValue("tableId")
+	playerId := r.PathValue("playerId")
+
+	if !validateIds(w, r, tableId, playerId) {
+		return
+	}
+	if !a.authorizePlayer(w, r, tableId, playerId) {
+		return
+	}
+
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx, cancel, err := commandContext(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer cancel()
+	player, err := table.ToggleReady(ctx, playerId)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(player); err != nil {
+		writeError(w, r, fmt.Errorf("failed to encode response: %w", err))
+		return
+	}
+	slog.Debug("Toggled readiness for player", "playerId", playerId)
+}
+
+// SetPlayerReady makes the player ready or not ready. Cards are dealt once all players are ready.
+func (a *RestApi) SetPlayerReady(w http.ResponseWriter, r *http.Request) {
 	tableId := r.PathValue("tableId")
 	playerId := r.PathValue("playerId")
 
-	game, ok := a.Games[tableId]
-	if !ok {
-		http.Error(w, "Game not found", http.StatusNotFound)
+	if !validateIds(w, r, tableId, playerId) {
+		return
+	}
+	if !a.authorizePlayer(w, r, tableId, playerId) {
+		return
+	}
+
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var reqData SetPlayerReadyRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		writeError(w, r, invalidArgument("Invalid request body: %v", err))
 		return
 	}
 
-	player, err := game.TogglePlayerReady(playerId)
+	ctx, cancel, err := commandContext(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to toggle readiness: %v", err), http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+	defer cancel()
+	player, err := table.SetReady(ctx, playerId, reqData.Ready)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(player); err != nil {
-		slog.Error(fmt.Sprintf("Failed to encode response: %v", err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, r, fmt.Errorf("failed to encode response: %w", err))
 		return
 	}
-	slog.Debug("Toggled readiness for player", "playerId", playerId)
+	slog.Debug("Set readiness for player", "playerId", playerId, "ready", reqData.Ready)
 }
 
-// nolint: cyclop
+// PlayerAction makes the player hit or stand.
 func (a *RestApi) PlayerAction(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	var reqData PlayerActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		writeError(w, r, invalidArgument("Invalid request body: %v", err))
+		return
 	}
+	a.act(w, r, reqData.Action)
+}
+
+// legacyPlayerAction takes the action from the "action" query parameter.
+func (a *RestApi) legacyPlayerAction(w http.ResponseWriter, r *http.Request) {
+	a.act(w, r, r.URL.Query().Get("action"))
+}
 
+func (a *RestApi) act(w http.ResponseWriter, r *http.Request, action string) {
 	tableId := r.PathValue("tableId")
 	playerId := r.PathValue("playerId")
 
-	game, ok := a.Games[tableId]
-	if !ok {
-		http.Error(w, "Game not found", http.StatusNotFound)
+	if !validateIds(w, r, tableId, playerId) {
+		return
+	}
+	if !a.authorizePlayer(w, r, tableId, playerId) {
 		return
 	}
 
-	action := r.URL.Query().Get("action")
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
+	var playerAction blackjack.Action
 	switch action {
 	case "hit":
-		if err := game.PlayerAction(playerId, blackjack.Hit); err != nil {
-			http.Error(w, fmt.Sprintf("Invalid action: %v", err), http.StatusInternalServerError)
-			return
-		}
-		slog.Debug("Player hit", "playerId", playerId)
+		playerAction = blackjack.Hit
 	case "stand":
-		if err := game.PlayerAction(playerId, blackjack.Stand); err != nil {
-			http.Error(w, fmt.Sprintf("Invalid action: %v", err), http.StatusInternalServerError)
-			return
-		}
-		slog.Debug("Player stood", "playerId", playerId)
+		playerAction = blackjack.Stand
 	default:
-		http.Error(w, "Invalid action", http.StatusBadRequest)
+		writeError(w, r, invalidArgument("Invalid action"))
 		return
 	}
-}
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Accepting all requests
-	},
+	ctx, cancel, err := commandContext(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer cancel()
+	if err := table.Act(ctx, playerId, playerAction); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	slog.Debug("Player acted", "playerId", playerId, "action", action)
 }
 
-func (a *RestApi) AddStateObserver(w http.ResponseWriter, r *http.Request) {
-	tableId := r.PathValue("tableId")
-
-	ws, _ := upgrader.Upgrade(w, r, nil)
-	gameSubscribers, ok := a.Websockets[tableId]
-	if ok {
-		a.Websockets[tableId] = append(gameSubscribers, ws)
-	} else {
-		a.Websockets[tableId] = []*websocket.Conn{ws}
+// authorizePlayer checks the session token from the Authorization header
+// and writes an error response if it does not grant access to the player.
+func (a *RestApi) authorizePlayer(w http.ResponseWriter, r *http.Request, tableId string, playerId string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		writeError(w, r, blackjack.NewError(blackjack.CodeUnauthenticated, "Missing session token"))
+		return false
 	}
+	if err := a.Signer.Authorize(token, tableId, playerId); err != nil {
+		writeError(w, r, authError(err))
+		return false
+	}
+	return true
+}
 
-	slog.Debug("Created a websocket for state updates", "tableId", tableId)
+// authError gives the error returned by the signer a code.
+func authError(err error) error {
+	if errors.Is(err, auth.ErrTokenMismatch) {
+		return blackjack.NewError(blackjack.CodePermissionDenied, "%v", err)
+	}
+	return blackjack.NewError(blackjack.CodeUnauthenticated, "%v", err)
 }
 
-func getRandomId() string {
-	id, err := rand.Int(rand.Reader, big.NewInt(constant.MaxId))
-	if err != nil {
-		panic(err)
+// validateIds writes a 400 response if the table id or any of the player ids is malformed.
+func validateIds(w http.ResponseWriter, r *http.Request, tableId string, playerIds ...string) bool {
+	if !ids.ValidTableId(tableId) {
+		writeError(w, r, invalidArgument("Invalid table id"))
+		return false
+	}
+	for _, playerId := range playerIds {
+		if !ids.ValidPlayerId(playerId) {
+			writeError(w, r, invalidArgument("Invalid player id"))
+			return false
+		}
 	}
-	return strconv.Itoa(int(id.Int64()))
+	return true
 }
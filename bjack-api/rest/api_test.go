@@ -3,18 +3,48 @@ package rest_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/GRO4T/bjack-api/auth"
 	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/constant"
+	"github.com/GRO4T/bjack-api/deck"
+	"github.com/GRO4T/bjack-api/registry"
 	"github.com/GRO4T/bjack-api/rest"
+	"github.com/gorilla/websocket"
 )
 
+const (
+	testTableId = "ABC234"
+)
+
+func newTestApi(options ...func(*rest.RestApi)) *rest.RestApi {
+	return rest.NewApi(auth.NewSigner([]byte("secret"), time.Hour), registry.New(), options...)
+}
+
+func setSessionToken(t *testing.T, api *rest.RestApi, request *http.Request, tableId string, playerId string) {
+	t.Helper()
+	token, err := api.Signer.Issue(tableId, playerId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+}
+
 func TestCreateGame(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	server := httptest.NewServer(http.HandlerFunc(api.CreateGame))
 	body := rest.CreateGameRequest{PlayerName: "Player 1"}
 	bodyBytes, err := json.Marshal(body)
@@ -33,31 +63,34 @@ func TestCreateGame(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("Expected status OK; got %v\n", resp.Status)
 	}
-	if len(api.Games) == 0 {
+	if api.Tables.Len() == 0 {
 		t.Fatal("Game not created")
 	}
-	for _, game := range api.Games {
-		if len(game.Players) > 0 {
-			t.Errorf("Expected 0 players; got %v", len(game.Players))
-		}
-		if len(game.Hands) != 1 { // Game starts with a dealer hand
-			t.Errorf("Expected 1 hands; got %v", len(game.Hands))
-		}
+	for _, table := range api.Tables.All() {
+		_ = table.Do(context.Background(), func(game *blackjack.Blackjack) error {
+			if len(game.Players) > 0 {
+				t.Errorf("Expected 0 players; got %v", len(game.Players))
+			}
+			if len(game.Hands) != 1 { // Game starts with a dealer hand
+				t.Errorf("Expected 1 hands; got %v", len(game.Hands))
+			}
+			return nil
+		})
 	}
 }
 
 func TestGetGameState(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	game := blackjack.New(nil)
-	api.Games["1"] = &game
+	api.Tables.Put(testTableId, &game)
 
 	// Act
-	request, err := http.NewRequest(http.MethodGet, "/tables/{tableId}", nil)
+	request, err := http.NewRequest(http.MethodGet, "/v1/tables/{tableId}", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	request.SetPathValue("tableId", "1")
+	request.SetPathValue("tableId", testTableId)
 	responseWriter := httptest.NewRecorder()
 	api.GetGameState(responseWriter, request)
 	resp := responseWriter.Result()
@@ -73,6 +106,27 @@ func TestGetGameState(t *testing.T) {
 	}
 }
 
+func TestGetGameStateWithInvalidTableId(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+
+	// Act
+	request, err := http.NewRequest(http.MethodGet, "/v1/tables/{tableId}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.SetPathValue("tableId", "../1")
+	responseWriter := httptest.NewRecorder()
+	api.GetGameState(responseWriter, request)
+	resp := responseWriter.Result()
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400; got %v\n", resp.Status)
+	}
+}
+
 func buildAddPlayerRequest(t *testing.T, tableId string, playerName string) *http.Request {
 	t.Helper()
 	body := rest.AddPlayerRequest{PlayerName: playerName}
@@ -80,7 +134,7 @@ func buildAddPlayerRequest(t *testing.T, tableId string, playerName string) *htt
 	if err != nil {
 		t.Fatal(err)
 	}
-	request, err := http.NewRequest(http.MethodPost, "/tables/players/{tableId}", bytes.NewReader(bodyBytes))
+	request, err := http.NewRequest(http.MethodPost, "/v1/tables/{tableId}/players", bytes.NewReader(bodyBytes))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -88,24 +142,25 @@ func buildAddPlayerRequest(t *testing.T, tableId string, playerName string) *htt
 	return request
 }
 
-func buildRemovePlayerRequest(t *testing.T, tableId string, playerId string) *http.Request {
+func buildRemovePlayerRequest(t *testing.T, api *rest.RestApi, tableId string, playerId string) *http.Request {
 	t.Helper()
-	request, err := http.NewRequest(http.MethodDelete, "/tables/players/{tableId}/{playerId}", nil)
+	request, err := http.NewRequest(http.MethodDelete, "/v1/tables/{tableId}/players/{playerId}", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	request.SetPathValue("tableId", tableId)
 	request.SetPathValue("playerId", playerId)
+	setSessionToken(t, api, request, tableId, playerId)
 	return request
 }
 
 func TestAddPlayer(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	game := blackjack.New(nil)
-	api.Games["1"] = &game
+	api.Tables.Put(testTableId, &game)
 	responseWriter := httptest.NewRecorder()
-	request := buildAddPlayerRequest(t, "1", "Player 1")
+	request := buildAddPlayerRequest(t, testTableId, "Player 1")
 
 	// Act
 	api.AddPlayer(responseWriter, request)
@@ -116,21 +171,21 @@ func TestAddPlayer(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("Expected status OK; got %v\n", resp.Status)
 	}
-	if len(api.Games["1"].Players) != 1 {
-		t.Errorf("Expected 1 player; got %v", len(api.Games["1"].Players))
+	if len(game.Players) != 1 {
+		t.Errorf("Expected 1 player; got %v", len(game.Players))
 	}
 }
 
 func TestRemovePlayer(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	game := blackjack.New(nil)
-	api.Games["1"] = &game
+	api.Tables.Put(testTableId, &game)
 
 	newPlayer, _ := game.AddPlayer("Player 1")
 
 	removePlayerResponseWriter := httptest.NewRecorder()
-	removePlayerRequest := buildRemovePlayerRequest(t, "1", newPlayer.Id)
+	removePlayerRequest := buildRemovePlayerRequest(t, api, testTableId, newPlayer.Id)
 
 	// Act
 	api.RemovePlayer(removePlayerResponseWriter, removePlayerRequest)
@@ -141,16 +196,16 @@ func TestRemovePlayer(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("Expected status OK; got %v\n", resp.Status)
 	}
-	if len(api.Games["1"].Players) != 0 {
-		t.Errorf("Expected 0 players; got %v", len(api.Games["1"].Players))
+	if len(game.Players) != 0 {
+		t.Errorf("Expected 0 players; got %v", len(game.Players))
 	}
 }
 
 func TestRemovePlayerWhenGameAlreadyStarted(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	game := blackjack.New(nil)
-	api.Games["1"] = &game
+	api.Tables.Put(testTableId, &game)
 
 	newPlayer, _ := game.AddPlayer("Player 1")
 	_, err := game.TogglePlayerReady(newPlayer.Id)
@@ -159,7 +214,7 @@ func TestRemovePlayerWhenGameAlreadyStarted(t *testing.T) {
 	}
 
 	removePlayerResponseWriter := httptest.NewRecorder()
-	removePlayerRequest := buildRemovePlayerRequest(t, "1", newPlayer.Id)
+	removePlayerRequest := buildRemovePlayerRequest(t, api, testTableId, newPlayer.Id)
 
 	// Act
 	api.RemovePlayer(removePlayerResponseWriter, removePlayerRequest)
@@ -167,8 +222,8 @@ func TestRemovePlayerWhenGameAlreadyStarted(t *testing.T) {
 	defer resp.Body.Close()
 
 	// Assert
-	if resp.StatusCode != http.StatusBadRequest {
-		t.Fatalf("Expected status 400; got %v\n", resp.Status)
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status 409; got %v\n", resp.Status)
 	}
 	if len(game.Players) != 1 {
 		t.Errorf("Expected 1 players; got %v", len(game.Players))
@@ -177,18 +232,19 @@ func TestRemovePlayerWhenGameAlreadyStarted(t *testing.T) {
 
 func TestTogglePlayerReadyWhenPlayerNotReady(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	game := blackjack.New(nil)
 	newPlayer, _ := game.AddPlayer("Player 1")
-	api.Games["1"] = &game
+	api.Tables.Put(testTableId, &game)
 
 	// Act
 	request, err := http.NewRequest(http.MethodPost, "/tables/ready/{tableId}/{playerId}", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	request.SetPathValue("tableId", "1")
+	request.SetPathValue("tableId", testTableId)
 	request.SetPathValue("playerId", newPlayer.Id)
+	setSessionToken(t, api, request, testTableId, newPlayer.Id)
 	responseWriter := httptest.NewRecorder()
 	api.TogglePlayerReady(responseWriter, request)
 	resp := responseWriter.Result()
@@ -212,20 +268,21 @@ func TestTogglePlayerReadyWhenPlayerNotReady(t *testing.T) {
 
 func TestTogglePlayerReadyWhenPlayerReady(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	game := blackjack.New(nil)
 	newPlayer, _ := game.AddPlayer("Player 1")
 	game.State = blackjack.WaitingForPlayers
-	api.Games["1"] = &game
-	api.Games["1"].Players[0].IsReady = true
+	api.Tables.Put(testTableId, &game)
+	game.Players[0].IsReady = true
 
 	// Act
 	request, err := http.NewRequest(http.MethodPost, "/tables/ready/{tableId}/{playerId}", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	request.SetPathValue("tableId", "1")
+	request.SetPathValue("tableId", testTableId)
 	request.SetPathValue("playerId", newPlayer.Id)
+	setSessionToken(t, api, request, testTableId, newPlayer.Id)
 	responseWriter := httptest.NewRecorder()
 	api.TogglePlayerReady(responseWriter, request)
 	resp := responseWriter.Result()
@@ -249,7 +306,7 @@ func TestTogglePlayerReadyWhenPlayerReady(t *testing.T) {
 
 func TestPlayerHit(t *testing.T) {
 	// Arrange
-	api := rest.NewApi()
+	api := newTestApi()
 	game := blackjack.New(nil)
 	newPlayer, _ := game.AddPlayer("Player 1")
 	err := game.Deal()
@@ -257,15 +314,16 @@ func TestPlayerHit(t *testing.T) {
 		t.Fatal(err)
 	}
 	game.State = blackjack.CardsDealt
-	api.Games["1"] = &game
+	api.Tables.Put(testTableId, &game)
 
 	// Act
-	request, err := http.NewRequest(http.MethodPost, "/tables/{tableId}/{playerId}?action=hit", nil)
+	request, err := http.NewRequest(http.MethodPost, "/v1/tables/{tableId}/players/{playerId}/actions", strings.NewReader(`{"action": "hit"}`))
 	if err != nil {
 		t.Fatal(err)
 	}
-	request.SetPathValue("tableId", "1")
+	request.SetPathValue("tableId", testTableId)
 	request.SetPathValue("playerId", newPlayer.Id)
+	setSessionToken(t, api, request, testTableId, newPlayer.Id)
 	responseWriter := httptest.NewRecorder()
 	api.PlayerAction(responseWriter, request)
 	resp := responseWriter.Result()
@@ -278,14 +336,543 @@ func TestPlayerHit(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("Expected status OK; got %v\n", resp.Status)
 	}
-	if len(api.Games["1"].GetPlayerHand(0)) != 3 {
-		t.Errorf("Expected 3 cards; got %v", len(api.Games["1"].GetPlayerHand(0)))
+	if len(game.GetPlayerHand(0)) != 3 {
+		t.Errorf("Expected 3 cards; got %v", len(game.GetPlayerHand(0)))
+	}
+}
+
+func TestPlayerHitWithoutSessionToken(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	err := game.Deal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	api.Tables.Put(testTableId, &game)
+
+	// Act
+	request, err := http.NewRequest(http.MethodPost, "/v1/tables/{tableId}/players/{playerId}/actions", strings.NewReader(`{"action": "hit"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.SetPathValue("tableId", testTableId)
+	request.SetPathValue("playerId", newPlayer.Id)
+	responseWriter := httptest.NewRecorder()
+	api.PlayerAction(responseWriter, request)
+	resp := responseWriter.Result()
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401; got %v\n", resp.Status)
+	}
+	if len(game.GetPlayerHand(0)) != 2 {
+		t.Errorf("Expected 2 cards; got %v", len(game.GetPlayerHand(0)))
+	}
+}
+
+func TestRemovePlayerWithOtherPlayerSessionToken(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+
+	victim, _ := game.AddPlayer("Player 1")
+	attacker, _ := game.AddPlayer("Player 2")
+
+	removePlayerRequest := buildRemovePlayerRequest(t, api, testTableId, victim.Id)
+	setSessionToken(t, api, removePlayerRequest, testTableId, attacker.Id)
+	removePlayerResponseWriter := httptest.NewRecorder()
+
+	// Act
+	api.RemovePlayer(removePlayerResponseWriter, removePlayerRequest)
+	resp := removePlayerResponseWriter.Result()
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected status 403; got %v\n", resp.Status)
+	}
+	if len(game.Players) != 2 {
+		t.Errorf("Expected 2 players; got %v", len(game.Players))
+	}
+}
+
+func TestParallelRequests(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	var wg sync.WaitGroup
+
+	// Act
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			responseWriter := httptest.NewRecorder()
+			api.AddPlayer(responseWriter, buildAddPlayerRequest(t, testTableId, fmt.Sprintf("Player %v", i)))
+		}()
+		go func() {
+			defer wg.Done()
+			request := httptest.NewRequest(http.MethodGet, "/v1/tables/{tableId}", nil)
+			request.SetPathValue("tableId", testTableId)
+			responseWriter := httptest.NewRecorder()
+			api.GetGameState(responseWriter, request)
+			if responseWriter.Code != http.StatusOK {
+				t.Errorf("Expected status OK; got %v", responseWriter.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	if len(game.Players) != constant.MaxPlayers {
+		t.Errorf("Expected %v players; got %v", constant.MaxPlayers, len(game.Players))
+	}
+}
+
+func stateUpdatesUrl(t *testing.T, api *rest.RestApi, tableId string) string {
+	t.Helper()
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/tables/" + tableId + "/updates"
+}
+
+func dialStateUpdates(t *testing.T, api *rest.RestApi, tableId string) *websocket.Conn {
+	t.Helper()
+	return dialStateUpdatesWithToken(t, api, tableId, "")
+}
+
+func dialStateUpdatesWithToken(t *testing.T, api *rest.RestApi, tableId string, token string) *websocket.Conn {
+	t.Helper()
+	wsUrl := stateUpdatesUrl(t, api, tableId)
+	if token != "" {
+		wsUrl += "?token=" + token
+	}
+	ws, resp, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() {
+		ws.Close()
+	})
+	if err := ws.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+func readStateMessage(t *testing.T, ws *websocket.Conn) rest.StateMessage {
+	t.Helper()
+	var message rest.StateMessage
+	if err := ws.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestStateObserverReceivesSnapshotAndEvents(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	table := api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+	snapshot := readStateMessage(t, ws)
+
+	// Act
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := readStateMessage(t, ws)
+
+	// Assert
+	if snapshot.Type != registry.Snapshot || len(snapshot.State.Players) != 0 {
+		t.Errorf("Expected snapshot with 0 players; got %+v", snapshot)
+	}
+	if message.Type != registry.PlayerJoined || message.PlayerId != player.Id {
+		t.Errorf("Expected PlayerJoined message for %v; got %+v", player.Id, message)
+	}
+	if message.Seq != snapshot.Seq+1 || message.Version != snapshot.Version+1 {
+		t.Errorf("Expected seq and version to follow the snapshot; got %+v after %+v", message, snapshot)
+	}
+	if len(message.State.Players) != 1 {
+		t.Errorf("Expected 1 player; got %v", len(message.State.Players))
+	}
+}
+
+func TestStateObserverResumedWithMissedEvents(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	table := api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+	snapshot := readStateMessage(t, ws)
+	ws.Close()
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	wsUrl := stateUpdatesUrl(t, api, testTableId) + fmt.Sprintf("?since=%d", snapshot.Seq)
+	resumed, resp, err := websocket.DefaultDialer.Dial(wsUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	defer resumed.Close()
+	message := readStateMessage(t, resumed)
+
+	// Assert
+	if message.Type != registry.PlayerJoined || message.PlayerId != player.Id || message.Seq != snapshot.Seq+1 {
+		t.Errorf("Expected the missed PlayerJoined message; got %+v", message)
+	}
+}
+
+func TestStateObserversClosedWhenTableRemoved(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+	readStateMessage(t, ws)
+
+	// Act
+	api.Tables.Remove(testTableId)
+
+	// Assert
+	_, _, err := ws.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("Expected normal closure; got %v", err)
+	}
+}
+
+func TestStateObserversClosedOnShutdown(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+	readStateMessage(t, ws)
+
+	// Act
+	if err := api.Tables.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	_, _, err := ws.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected going away closure; got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := api.WaitForWebsockets(ctx); err != nil {
+		t.Error(err)
+	}
+}
+
+func waitForWebsockets(t *testing.T, api *rest.RestApi, tableId string, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for api.Websockets(tableId) != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %v websockets; got %v", expected, api.Websockets(tableId))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebsocketUnregisteredWhenClientDisconnects(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+	readStateMessage(t, ws)
+	waitForWebsockets(t, api, testTableId, 1)
+
+	// Act
+	ws.Close()
+
+	// Assert
+	waitForWebsockets(t, api, testTableId, 0)
+	if api.Tables.Subscribers(testTableId) != 0 {
+		t.Errorf("Expected 0 subscribers; got %v", api.Tables.Subscribers(testTableId))
+	}
+}
+
+func TestWebsocketPinged(t *testing.T) {
+	// Arrange
+	api := newTestApi(rest.WithPingInterval(20 * time.Millisecond))
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+	readStateMessage(t, ws)
+	var pings atomic.Int32
+	ws.SetPingHandler(func(data string) error {
+		pings.Add(1)
+		return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	// Act
+	if err := ws.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := ws.ReadMessage()
+
+	// Assert
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Expected the connection to stay open; got %v", err)
+	}
+	if pings.Load() == 0 {
+		t.Error("Expected the server to ping the websocket")
+	}
+}
+
+func TestDeadWebsocketDropped(t *testing.T) {
+	// Arrange
+	api := newTestApi(rest.WithPingInterval(20 * time.Millisecond))
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+	readStateMessage(t, ws)
+
+	// Act
+	ws.SetPingHandler(func(string) error {
+		return nil // Never answers.
+	})
+	_, _, err := ws.ReadMessage()
+
+	// Assert
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		t.Fatalf("Expected the server to drop the connection; got %v", err)
+	}
+	waitForWebsockets(t, api, testTableId, 0)
+}
+
+// sendCommand sends cmd over the websocket and returns the reply, skipping state messages.
+func sendCommand(t *testing.T, ws *websocket.Conn, cmd rest.Command) rest.Reply {
+	t.Helper()
+	if err := ws.WriteJSON(cmd); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reply rest.Reply
+		if err := json.Unmarshal(data, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Type == rest.ReplyMessage && reply.Id == cmd.Id {
+			return reply
+		}
+	}
+}
+
+func TestPlayOverWebsocket(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+
+	// Act
+	joined := sendCommand(t, ws, rest.Command{Id: "1", Type: rest.JoinCommand, PlayerName: "Player 1"})
+	ready := sendCommand(t, ws, rest.Command{Id: "2", Type: rest.ReadyCommand})
+	stood := sendCommand(t, ws, rest.Command{Id: "3", Type: rest.StandCommand})
+
+	// Assert
+	if !joined.Ok || joined.PlayerId == "" || joined.Token == "" {
+		t.Fatalf("Expected to join with a session token; got %+v", joined)
+	}
+	if !ready.Ok || ready.Player == nil || !ready.Player.IsReady {
+		t.Errorf("Expected the player to be ready; got %+v", ready)
+	}
+	if !stood.Ok {
+		t.Errorf("Expected to stand; got %+v", stood)
+	}
+	if game.State != blackjack.Finished {
+		t.Errorf("Expected the game to be finished; got %v", game.State)
+	}
+}
+
+func TestWebsocketCommandWithoutSessionToken(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	_, _ = game.AddPlayer("Player 1")
+	api.Tables.Put(testTableId, &game)
+	ws := dialStateUpdates(t, api, testTableId)
+
+	// Act
+	reply := sendCommand(t, ws, rest.Command{Id: "1", Type: rest.ReadyCommand})
+
+	// Assert
+	if reply.Ok || reply.Error != "Missing session token" || reply.Code != blackjack.CodeUnauthenticated {
+		t.Errorf("Expected missing session token error; got %+v", reply)
+	}
+	if game.Players[0].IsReady {
+		t.Error("Player is ready")
+	}
+}
+
+func TestWebsocketAuthenticatedOnConnect(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	api.Tables.Put(testTableId, &game)
+	token, err := api.Signer.Issue(testTableId, newPlayer.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := dialStateUpdatesWithToken(t, api, testTableId, token)
+
+	// Act
+	ready := sendCommand(t, ws, rest.Command{Id: "1", Type: rest.ReadyCommand})
+	hit := sendCommand(t, ws, rest.Command{Id: "2", Type: rest.HitCommand})
+	unknown := sendCommand(t, ws, rest.Command{Id: "3", Type: "fold"})
+
+	// Assert
+	if !ready.Ok {
+		t.Errorf("Expected the player to be ready; got %+v", ready)
+	}
+	if !hit.Ok || len(game.GetPlayerHand(0)) != 3 {
+		t.Errorf("Expected the player to hit; got %+v", hit)
+	}
+	if unknown.Ok {
+		t.Errorf("Expected an unknown command error; got %+v", unknown)
+	}
+}
+
+// dealtGame returns a game with two players whose cards are dealt.
+func dealtGame(t *testing.T) (*blackjack.Blackjack, blackjack.Player, blackjack.Player) {
+	t.Helper()
+	game := blackjack.New(nil)
+	first, _ := game.AddPlayer("Player 1")
+	second, _ := game.AddPlayer("Player 2")
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	return &game, *first, *second
+}
+
+func TestViewOf(t *testing.T) {
+	// Arrange
+	game, first, _ := dealtGame(t)
+	faceDown := deck.Card{}
+
+	tests := []struct {
+		name     string
+		playerId string
+		visible  []bool
+	}{
+		{"player", first.Id, []bool{true, true, false}},
+		{"spectator", "", []bool{true, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			view := rest.ViewOf(game, tt.playerId)
+
+			// Assert
+			if view.Hands[0][0] != game.Hands[0][0] || view.Hands[0][1] != faceDown {
+				t.Errorf("Expected only the dealer's up card; got %v", view.Hands[0])
+			}
+			for i, visible := range tt.visible[1:] {
+				hand := view.Hands[i+1]
+				if len(hand) != len(game.Hands[i+1]) {
+					t.Fatalf("Expected %v cards in hand %v; got %v", len(game.Hands[i+1]), i+1, len(hand))
+				}
+				if shown := hand[0] != faceDown; shown != visible {
+					t.Errorf("Expected hand %v to be shown: %v; got %v", i+1, visible, hand)
+				}
+			}
+			if game.Hands[2][0] == faceDown {
+				t.Error("Expected the game not to be modified")
+			}
+		})
+	}
+}
+
+func TestViewOfFinishedGame(t *testing.T) {
+	// Arrange
+	game, _, _ := dealtGame(t)
+	game.State = blackjack.Finished
+
+	// Act
+	view := rest.ViewOf(game, "")
+
+	// Assert
+	if view != game {
+		t.Errorf("Expected all cards to be shown once the round is finished; got %v", view.Hands)
+	}
+}
+
+func TestStateObserverSeesOwnCards(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game, first, second := dealtGame(t)
+	api.Tables.Put(testTableId, game)
+	token, err := api.Signer.Issue(testTableId, second.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := dialStateUpdatesWithToken(t, api, testTableId, token)
+
+	// Act
+	snapshot := readStateMessage(t, ws)
+
+	// Assert
+	hands := snapshot.State.Hands
+	if len(hands) != 3 {
+		t.Fatalf("Expected 3 hands; got %v", hands)
+	}
+	if hands[2][0] == (deck.Card{}) || hands[1][0] != (deck.Card{}) {
+		t.Errorf("Expected only the cards of %v to be shown, not of %v; got %v", second.Name, first.Name, hands)
+	}
+}
+
+func TestWebsocketWithOtherTableSessionToken(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	api.Tables.Put(testTableId, &game)
+	token, err := api.Signer.Issue("XYZ789", newPlayer.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	ws, resp, err := websocket.DefaultDialer.Dial(stateUpdatesUrl(t, api, testTableId)+"?token="+token, nil)
+
+	// Assert
+	if err == nil {
+		ws.Close()
+		t.Fatal("Expected the handshake to fail")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403; got %v", resp.StatusCode)
 	}
 }
 
 //nolint:cyclop
 func TestSimpleGame(t *testing.T) {
-	api := rest.NewApi()
+	api := newTestApi()
 
 	// Create game
 	createGameBody := rest.CreateGameRequest{PlayerName: "Player 1"}
@@ -293,7 +880,7 @@ func TestSimpleGame(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	createGameRequest, err := http.NewRequest(http.MethodPost, "/tables", bytes.NewReader(createGameBodyBytes))
+	createGameRequest, err := http.NewRequest(http.MethodPost, "/v1/tables", bytes.NewReader(createGameBodyBytes))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -316,7 +903,7 @@ func TestSimpleGame(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	addPlayerRequest, err := http.NewRequest(http.MethodPost, "/tables/players/{tableId}", bytes.NewReader(addPlayerBodyBytes))
+	addPlayerRequest, err := http.NewRequest(http.MethodPost, "/v1/tables/{tableId}/players", bytes.NewReader(addPlayerBodyBytes))
 	addPlayerRequest.SetPathValue("tableId", tableId)
 	if err != nil {
 		t.Fatal(err)
@@ -334,25 +921,29 @@ func TestSimpleGame(t *testing.T) {
 	}
 	playerId := addPlayerRespBody.PlayerId
 
-	// Toggle player ready
-	togglePlayerReadyRequest, err := http.NewRequest(http.MethodPost, "/tables/ready/{tableId}/{playerId}", nil)
-	togglePlayerReadyRequest.SetPathValue("tableId", tableId)
-	togglePlayerReadyRequest.SetPathValue("playerId", playerId)
+	// Set player ready
+	setPlayerReadyRequest, err := http.NewRequest(
+		http.MethodPut, "/v1/tables/{tableId}/players/{playerId}/ready", strings.NewReader(`{"ready": true}`))
 	if err != nil {
 		t.Fatal(err)
 	}
-	togglePlayerReadyResponseWriter := httptest.NewRecorder()
-	api.TogglePlayerReady(togglePlayerReadyResponseWriter, togglePlayerReadyRequest)
-	togglePlayerReadyResp := togglePlayerReadyResponseWriter.Result()
-	defer togglePlayerReadyResp.Body.Close()
-	if togglePlayerReadyResp.StatusCode != http.StatusOK {
-		t.Fatalf("Expected status OK; got %v\n", togglePlayerReadyResp.Status)
+	setPlayerReadyRequest.SetPathValue("tableId", tableId)
+	setPlayerReadyRequest.SetPathValue("playerId", playerId)
+	setPlayerReadyRequest.Header.Set("Authorization", "Bearer "+addPlayerRespBody.Token)
+	setPlayerReadyResponseWriter := httptest.NewRecorder()
+	api.SetPlayerReady(setPlayerReadyResponseWriter, setPlayerReadyRequest)
+	setPlayerReadyResp := setPlayerReadyResponseWriter.Result()
+	defer setPlayerReadyResp.Body.Close()
+	if setPlayerReadyResp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v\n", setPlayerReadyResp.Status)
 	}
 
 	// Player hit
-	playerHitRequest, err := http.NewRequest(http.MethodPost, "/tables/{tableId}/{playerId}?action=hit", nil)
+	playerHitRequest, err := http.NewRequest(
+		http.MethodPost, "/v1/tables/{tableId}/players/{playerId}/actions", strings.NewReader(`{"action": "hit"}`))
 	playerHitRequest.SetPathValue("tableId", tableId)
 	playerHitRequest.SetPathValue("playerId", playerId)
+	playerHitRequest.Header.Set("Authorization", "Bearer "+addPlayerRespBody.Token)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -365,7 +956,7 @@ func TestSimpleGame(t *testing.T) {
 	}
 
 	// Check game outcome
-	getGameStateRequest, err := http.NewRequest(http.MethodGet, "/tables/{tableId}", nil)
+	getGameStateRequest, err := http.NewRequest(http.MethodGet, "/v1/tables/{tableId}", nil)
 	getGameStateRequest.SetPathValue("tableId", tableId)
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GRO4T/bjack-api/constant"
+	"github.com/GRO4T/bjack-api/registry"
+)
+
+// etag returns the entity tag of a table at the given version.
+func etag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// commandContext returns the context of a table command sent on behalf of the request.
+// If the request has an If-Match header, the command fails unless the table is still
+// at the version from the header. A wildcard matches every version.
+func commandContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	ctx := r.Context()
+	if header := strings.TrimSpace(r.Header.Get("If-Match")); header != "" && header != "*" {
+		unquoted, err := strconv.Unquote(header)
+		if err != nil || !strings.HasPrefix(header, `"`) {
+			return nil, nil, invalidArgument("If-Match must be a single strong entity tag")
+		}
+		version, err := strconv.ParseUint(unquoted, 10, 64)
+		if err != nil {
+			return nil, nil, invalidArgument("If-Match does not name a table version")
+		}
+		ctx = registry.WithExpectedVersion(ctx, version)
+	}
+	ctx, cancel := context.WithTimeout(ctx, constant.CommandTimeout)
+	return ctx, cancel, nil
+}
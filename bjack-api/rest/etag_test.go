@@ -0,0 +1,109 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/rest"
+)
+
+func getETag(t *testing.T, api *rest.RestApi, tableId string) string {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, "/v1/tables/{tableId}", nil)
+	request.SetPathValue("tableId", tableId)
+	responseWriter := httptest.NewRecorder()
+	api.GetGameState(responseWriter, request)
+	resp := responseWriter.Result()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", resp.Status)
+	}
+	return resp.Header.Get("ETag")
+}
+
+func TestGetGameStateReturnsETag(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	before := getETag(t, api, testTableId)
+	table, err := api.Tables.Get(testTableId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := table.Join(context.Background(), "Player 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	after := getETag(t, api, testTableId)
+
+	// Assert
+	if before != `"0"` {
+		t.Errorf(`Expected ETag "0"; got %v`, before)
+	}
+	if after != `"1"` {
+		t.Errorf(`Expected ETag "1"; got %v`, after)
+	}
+}
+
+func TestAddPlayerWithIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch func(current string) string
+		status  int
+		code    blackjack.Code
+	}{
+		{"current version", func(current string) string { return current }, http.StatusOK, ""},
+		{"wildcard", func(string) string { return "*" }, http.StatusOK, ""},
+		{"stale version", func(string) string { return `"0"` }, http.StatusPreconditionFailed, blackjack.CodeVersionMismatch},
+		{"malformed", func(string) string { return "1" }, http.StatusBadRequest, blackjack.CodeInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			api := newTestApi()
+			game := blackjack.New(nil)
+			if _, err := game.AddPlayer("Player 1"); err != nil {
+				t.Fatal(err)
+			}
+			api.Tables.Put(testTableId, &game)
+			table, err := api.Tables.Get(testTableId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := table.Join(context.Background(), "Player 2"); err != nil {
+				t.Fatal(err)
+			}
+			request := buildAddPlayerRequest(t, testTableId, "Player 3")
+			request.Header.Set("If-Match", tt.ifMatch(getETag(t, api, testTableId)))
+			responseWriter := httptest.NewRecorder()
+
+			// Act
+			api.AddPlayer(responseWriter, request)
+			resp := responseWriter.Result()
+			defer resp.Body.Close()
+
+			// Assert
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected status %v; got %v", tt.status, resp.Status)
+			}
+			if tt.code == "" {
+				return
+			}
+			if problem := readProblem(t, resp); problem.Code != tt.code {
+				t.Errorf("Expected %v; got %+v", tt.code, problem)
+			}
+			_ = table.Do(context.Background(), func(game *blackjack.Blackjack) error {
+				if len(game.Players) != 2 {
+					t.Errorf("Expected the player not to join; got %v players", len(game.Players))
+				}
+				return nil
+			})
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GRO4T/bjack-api/constant"
+	"github.com/GRO4T/bjack-api/registry"
+)
+
+// StreamEvents streams the state of the table as Server-Sent Events. Every event carries
+// a StateMessage with the event's sequence number as its id. The stream starts with a snapshot,
+// unless the client reconnects with a Last-Event-ID header. Then it gets the events it missed,
+// or a snapshot if these are no longer kept.
+// nolint: cyclop
+func (a *RestApi) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	tableId := r.PathValue("tableId")
+
+	if !validateIds(w, r, tableId) {
+		return
+	}
+
+	var lastSeq uint64
+	resuming := false
+	if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+		seq, err := strconv.ParseUint(lastEventId, 10, 64)
+		if err != nil {
+			writeError(w, r, invalidArgument("Invalid Last-Event-ID"))
+			return
+		}
+		lastSeq = seq
+		resuming = true
+	}
+
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), constant.CommandTimeout)
+	catchUp, events, unsubscribe, err := a.Tables.Watch(ctx, table, resuming, lastSeq)
+	cancel()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the write timeout meant for regular requests.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("Failed to clear write deadline", "error", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for _, event := range catchUp {
+		if !writeEvent(w, rc, event) {
+			return
+		}
+		lastSeq = event.Seq
+	}
+
+	ticker := time.NewTicker(a.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			// Keeps proxies from timing out the connection and detects dead clients.
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for being too slow, the client can resume with Last-Event-ID.
+				return
+			}
+			if event.Seq <= lastSeq {
+				continue
+			}
+			if !writeEvent(w, rc, event) || event.Type == registry.TableClosed || event.Type == registry.ServerShutdown {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event registry.Event) bool {
+	data, err := json.Marshal(stateMessage(event))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to encode event: %v", err))
+		return false
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data); err != nil {
+		slog.Debug("Failed to send event", "error", err)
+		return false
+	}
+	if err := rc.Flush(); err != nil {
+		slog.Debug("Failed to flush event", "error", err)
+		return false
+	}
+	return true
+}
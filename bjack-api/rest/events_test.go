@@ -0,0 +1,194 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/GRO4T/bjack-api/rest"
+)
+
+type sseEvent struct {
+	id      uint64
+	name    string
+	message rest.StateMessage
+}
+
+func openEventStream(t *testing.T, api *rest.RestApi, tableId string, lastEventId string) *bufio.Reader {
+	t.Helper()
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/tables/"+tableId+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventId != "" {
+		request.Header.Set("Last-Event-ID", lastEventId)
+	}
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		resp.Body.Close()
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream; got %v", contentType)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.name != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id, err = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err = json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.message)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	table := api.Tables.Put(testTableId, &game)
+	reader := openEventStream(t, api, testTableId, "")
+	snapshot := readEvent(t, reader)
+
+	// Act
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := readEvent(t, reader)
+
+	// Assert
+	if snapshot.name != string(registry.Snapshot) || snapshot.id != snapshot.message.Seq {
+		t.Errorf("Expected snapshot; got %+v", snapshot)
+	}
+	if event.name != string(registry.PlayerJoined) || event.message.PlayerId != player.Id {
+		t.Errorf("Expected PlayerJoined event for %v; got %+v", player.Id, event)
+	}
+	if event.id != snapshot.id+1 || len(event.message.State.Players) != 1 {
+		t.Errorf("Expected next event with 1 player; got %+v", event)
+	}
+}
+
+func TestStreamEventsResumedWithoutChanges(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	table := api.Tables.Put(testTableId, &game)
+	if _, err := table.Join(context.Background(), "Player 1"); err != nil {
+		t.Fatal(err)
+	}
+	seen, err := table.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := openEventStream(t, api, testTableId, strconv.FormatUint(seen.Seq, 10))
+
+	// Act
+	if _, err := table.Join(context.Background(), "Player 2"); err != nil {
+		t.Fatal(err)
+	}
+	event := readEvent(t, reader)
+
+	// Assert
+	if event.name != string(registry.PlayerJoined) || len(event.message.State.Players) != 2 {
+		t.Errorf("Expected PlayerJoined event without a snapshot first; got %+v", event)
+	}
+}
+
+func TestStreamEventsEndsWhenTableRemoved(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	reader := openEventStream(t, api, testTableId, "")
+	readEvent(t, reader)
+
+	// Act
+	api.Tables.Remove(testTableId)
+
+	// Assert
+	event := readEvent(t, reader)
+	if event.name != string(registry.TableClosed) {
+		t.Errorf("Expected TableClosed event; got %+v", event)
+	}
+}
+
+func TestStreamEventsWithInvalidLastEventId(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	request := httptest.NewRequest(http.MethodGet, "/v1/tables/{tableId}/events", nil)
+	request.SetPathValue("tableId", testTableId)
+	request.Header.Set("Last-Event-ID", "abc")
+	responseWriter := httptest.NewRecorder()
+
+	// Act
+	api.StreamEvents(responseWriter, request)
+
+	// Assert
+	if responseWriter.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400; got %v", responseWriter.Code)
+	}
+}
+
+func TestStreamEventsResumedWithMissedEvents(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	table := api.Tables.Put(testTableId, &game)
+	seen, err := table.Snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := table.Join(context.Background(), "Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	reader := openEventStream(t, api, testTableId, strconv.FormatUint(seen.Seq, 10))
+	event := readEvent(t, reader)
+
+	// Assert
+	if event.name != string(registry.PlayerJoined) || event.message.PlayerId != player.Id {
+		t.Errorf("Expected the missed PlayerJoined event; got %+v", event)
+	}
+	if event.id != seen.Seq+1 {
+		t.Errorf("Expected event %v; got %v", seen.Seq+1, event.id)
+	}
+}
@@ -0,0 +1,16 @@
+package rest
+
+// Patterns returns the patterns of the API endpoints registered by Handler.
+func (a *RestApi) Patterns() []string {
+	patterns := make([]string, 0, len(a.routes()))
+	for _, route := range a.routes() {
+		patterns = append(patterns, route.pattern)
+	}
+	return patterns
+}
+
+// ErrorReply returns the websocket reply to a failed command.
+var ErrorReply = errorReply
+
+// ViewOf returns the game as seen by the player over a websocket.
+var ViewOf = viewOf
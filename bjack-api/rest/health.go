@@ -0,0 +1,35 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// Healthz answers liveness probes. The server is alive as long as it answers.
+func (a *RestApi) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w)
+}
+
+// Readyz answers readiness probes with 200 while the tables are ready
+// and with a 503 problem otherwise, e.g. while the server is shutting down.
+func (a *RestApi) Readyz(w http.ResponseWriter, r *http.Request) {
+	if err := a.Tables.Ready(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeHealth(w)
+}
+
+func writeHealth(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(HealthResponse{Status: "ok"}); err != nil {
+		slog.Error(fmt.Sprintf("Failed to write response: %v", err))
+	}
+}
@@ -0,0 +1,47 @@
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+)
+
+func TestHealthz(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	recorder := httptest.NewRecorder()
+
+	// Act
+	api.Healthz(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	// Assert
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", recorder.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	readyz := api.Readyz
+	ready := httptest.NewRecorder()
+	readyz(ready, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	draining := httptest.NewRecorder()
+
+	// Act
+	api.Tables.Drain()
+	readyz(draining, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// Assert
+	if ready.Code != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", ready.Code)
+	}
+	if draining.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 while draining; got %v", draining.Code)
+	}
+	if problem := readProblem(t, draining.Result()); problem.Code != blackjack.CodeTableUnavailable {
+		t.Errorf("Expected %v; got %+v", blackjack.CodeTableUnavailable, problem)
+	}
+}
@@ -0,0 +1,207 @@
+package rest
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+	// Number of replies that can wait for the writer before the connection is dropped.
+	// Events are queued by the table subscription.
+	wsSendQueueLen = 16
+	// DefaultPingInterval is how often websockets are pinged. A connection that does not
+	// answer within two intervals is considered dead.
+	DefaultPingInterval = 30 * time.Second
+	// How often WaitForWebsockets checks whether all websockets are closed.
+	websocketPollInterval = 50 * time.Millisecond
+)
+
+// hub keeps track of the open websockets of every table.
+type hub struct {
+	mu    sync.Mutex
+	conns map[string]map[*wsConn]struct{}
+}
+
+func newHub() *hub {
+	return &hub{conns: map[string]map[*wsConn]struct{}{}}
+}
+
+func (h *hub) register(conn *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conns[conn.tableId] == nil {
+		h.conns[conn.tableId] = map[*wsConn]struct{}{}
+	}
+	h.conns[conn.tableId][conn] = struct{}{}
+}
+
+func (h *hub) unregister(conn *wsConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns[conn.tableId], conn)
+	if len(h.conns[conn.tableId]) == 0 {
+		delete(h.conns, conn.tableId)
+	}
+}
+
+func (h *hub) count(tableId string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.conns[tableId])
+}
+
+func (h *hub) total() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, conns := range h.conns {
+		n += len(conns)
+	}
+	return n
+}
+
+// wsConn is a websocket with a single writer goroutine. Other goroutines
+// hand messages over through a bounded queue and never write to the socket.
+type wsConn struct {
+	ws        *websocket.Conn
+	tableId   string
+	send      chan any
+	done      chan struct{}
+	closeOnce sync.Once
+	// Close frame sent by the writer once done is closed. Nil if the peer is already gone.
+	closeMessage []byte
+	// Player the connection acts for, set by the reader and read by the writer.
+	playerId atomic.Pointer[string]
+}
+
+func newWsConn(ws *websocket.Conn, tableId string) *wsConn {
+	return &wsConn{
+		ws:      ws,
+		tableId: tableId,
+		send:    make(chan any, wsSendQueueLen),
+		done:    make(chan struct{}),
+	}
+}
+
+// setPlayer binds the connection to the player, or unbinds it if playerId is empty.
+func (c *wsConn) setPlayer(playerId string) {
+	c.playerId.Store(&playerId)
+}
+
+// stateMessage returns the message of the event with the game as seen by the player
+// the connection acts for.
+func (c *wsConn) stateMessage(event registry.Event) StateMessage {
+	var playerId string
+	if p := c.playerId.Load(); p != nil {
+		playerId = *p
+	}
+	message := stateMessage(event)
+	message.State = viewOf(message.State, playerId)
+	return message
+}
+
+// enqueue queues v for the writer. A connection that cannot keep up is closed.
+func (c *wsConn) enqueue(v any) {
+	select {
+	case c.send <- v:
+	case <-c.done:
+	default:
+		slog.Warn("Dropping slow websocket", "tableId", c.tableId)
+		c.close(websocket.CloseTryAgainLater, "Too slow")
+	}
+}
+
+// close makes the writer send a close frame and disconnect.
+func (c *wsConn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeMessage = websocket.FormatCloseMessage(code, reason)
+		close(c.done)
+	})
+}
+
+// abort makes the writer disconnect without a close frame.
+func (c *wsConn) abort() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// writeLoop sends the catch up events followed by the table's events and queued messages,
+// pinging the peer every pingInterval, until the connection is closed.
+// nolint: cyclop
+func (c *wsConn) writeLoop(
+	catchUp []registry.Event,
+	lastSeq uint64,
+	events <-chan registry.Event,
+	pingInterval time.Duration,
+) {
+	defer c.ws.Close()
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for _, event := range catchUp {
+		c.write(c.stateMessage(event))
+		lastSeq = event.Seq
+	}
+
+	for {
+		select {
+		case <-c.done:
+			c.writeClose()
+			return
+		case v := <-c.send:
+			c.write(v)
+		case event, ok := <-events:
+			switch {
+			case !ok:
+				// The registry drops subscribers that do not keep up.
+				c.close(websocket.CloseTryAgainLater, "Too slow")
+			case event.Seq <= lastSeq:
+				// Already caught up.
+			case event.Type == registry.TableClosed:
+				c.close(websocket.CloseNormalClosure, "Table closed")
+			case event.Type == registry.ServerShutdown:
+				c.close(websocket.CloseGoingAway, "Server shutting down")
+			default:
+				c.write(c.stateMessage(event))
+			}
+			if !ok {
+				events = nil
+			}
+		case <-ticker.C:
+			deadline := time.Now().Add(wsWriteTimeout)
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				slog.Debug("Failed to ping websocket", "error", err)
+				c.abort()
+			}
+		}
+	}
+}
+
+func (c *wsConn) write(v any) {
+	if err := c.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		slog.Debug("Failed to set websocket write deadline", "error", err)
+		c.abort()
+		return
+	}
+	if err := c.ws.WriteJSON(v); err != nil {
+		slog.Debug("Failed to send data via websocket", "error", err)
+		c.abort()
+	}
+}
+
+func (c *wsConn) writeClose() {
+	if c.closeMessage == nil {
+		return
+	}
+	deadline := time.Now().Add(time.Second)
+	if err := c.ws.WriteControl(websocket.CloseMessage, c.closeMessage, deadline); err != nil {
+		slog.Debug("Failed to send close message via websocket", "error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/GRO4T/bjack-api/idempotency"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+	maxIdempotentBody    = 1 << 20
+)
+
+// SavedResponse is a response kept for replaying to retried requests.
+type SavedResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Idempotent makes POST, PUT and DELETE requests with an Idempotency-Key header
+// execute at most once within the window of the cache. Retries get the response of
+// the first request, marked with an Idempotent-Replayed header. Server errors are not
+// kept, so that a retry can succeed. Keys are scoped to the Authorization header.
+// Requests without one share a key space, so responses marked Cache-Control: no-store,
+// like those issuing session tokens, are not kept either.
+func Idempotent(cache *idempotency.Cache[SavedResponse], next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			writeError(w, r, invalidArgument("%s must not be longer than %d characters",
+				idempotencyKeyHeader, idempotency.MaxKeyLength))
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			writeError(w, r, invalidArgument("Invalid request body: %v", err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := r.Header.Get("Authorization") + "\x00" + key
+		recorder := &responseRecorder{ResponseWriter: w}
+		saved, replayed, err := cache.Do(r.Context(), scope, fingerprint(r, body), func() (SavedResponse, bool) {
+			next.ServeHTTP(recorder, r)
+			response := recorder.saved()
+			return response, response.Status < http.StatusInternalServerError && !isNoStore(response.Header)
+		})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if !replayed {
+			return
+		}
+		for name, values := range saved.Header {
+			if _, ok := w.Header()[name]; !ok {
+				w.Header()[name] = values
+			}
+		}
+		w.Header().Set(replayedHeader, "true")
+		w.WriteHeader(saved.Status)
+		if _, err := w.Write(saved.Body); err != nil {
+			slog.Error(fmt.Sprintf("Failed to write response: %v", err))
+		}
+		slog.Debug("Replayed response", "method", r.Method, "path", r.URL.Path)
+	})
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isNoStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint tells a retry, which has the same method, URL and body, from another request.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b) //nolint: wrapcheck
+}
+
+func (r *responseRecorder) saved() SavedResponse {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return SavedResponse{Status: status, Header: r.Header().Clone(), Body: r.body.Bytes()}
+}
@@ -0,0 +1,172 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/idempotency"
+	"github.com/GRO4T/bjack-api/rest"
+)
+
+func newIdempotentServer(t *testing.T, api *rest.RestApi) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(rest.Idempotent(idempotency.NewCache[rest.SavedResponse](time.Hour), api.Handler()))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetriedCreateGame(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	server := newIdempotentServer(t, api)
+	createGame := func() (*http.Response, rest.CreateGameResponse) {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodPost, server.URL+"/v1/tables", strings.NewReader(`{"playerName": "Player 1"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Idempotency-Key", "key-1")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body rest.CreateGameResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+	_, first := createGame()
+
+	// Act
+	resp, second := createGame()
+
+	// Assert
+	if resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the response to be replayed")
+	}
+	if second.TableId != first.TableId {
+		t.Errorf("Expected table %v; got %v", first.TableId, second.TableId)
+	}
+	if api.Tables.Len() != 1 {
+		t.Errorf("Expected 1 table; got %v", api.Tables.Len())
+	}
+}
+
+func TestRetriedPlayerAction(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	api.Tables.Put(testTableId, &game)
+	server := newIdempotentServer(t, api)
+	hit := func() *http.Response {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodPost, server.URL+"/tables/"+testTableId+"/"+newPlayer.Id+"?action=hit", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setSessionToken(t, api, request, testTableId, newPlayer.Id)
+		request.Header.Set("Idempotency-Key", "key-1")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	hit()
+
+	// Act
+	resp := hit()
+
+	// Assert
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+	if len(game.GetPlayerHand(0)) != 3 {
+		t.Errorf("Expected 3 cards; got %v", len(game.GetPlayerHand(0)))
+	}
+}
+
+func TestIdempotencyKeyReusedForOtherRequest(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	server := newIdempotentServer(t, api)
+	createGame := func(body string) *http.Response {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodPost, server.URL+"/v1/tables", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Idempotency-Key", "key-1")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	createGame(`{"playerName": "Player 1"}`).Body.Close()
+
+	// Act
+	resp := createGame(`{"playerName": "Player 2"}`)
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422; got %v", resp.Status)
+	}
+	if problem := readProblem(t, resp); problem.Code != blackjack.CodeIdempotencyKeyReused {
+		t.Errorf("Expected %v; got %+v", blackjack.CodeIdempotencyKeyReused, problem)
+	}
+	if api.Tables.Len() != 1 {
+		t.Errorf("Expected 1 table; got %v", api.Tables.Len())
+	}
+}
+
+func TestAddPlayerResponseNotKept(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	server := newIdempotentServer(t, api)
+	addPlayer := func() *http.Response {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodPost, server.URL+"/v1/tables/"+testTableId+"/players",
+			strings.NewReader(`{"playerName": "Player 1"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.Header.Set("Idempotency-Key", "key-1")
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	first := addPlayer()
+	first.Body.Close()
+
+	// Act
+	resp := addPlayer()
+	defer resp.Body.Close()
+
+	// Assert
+	if first.Header.Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the session token not to be stored; got Cache-Control %q", first.Header.Get("Cache-Control"))
+	}
+	if resp.StatusCode != http.StatusConflict || resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("Expected the name to be taken rather than the session token to be replayed; got %v", resp.Status)
+	}
+}
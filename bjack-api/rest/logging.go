@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/requestid"
+)
+
+// Logged gives every request an id, keeping the one in the X-Request-Id header if it is valid,
+// and echoes it in the response. Once a request is served, it is logged together with its
+// route in mux, status, latency and the table and player it was about. Server errors are
+// logged as errors, except for unavailable tables, which are expected e.g. while draining.
+func Logged(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := requestid.Ensure(r.Header.Get(requestid.Header))
+		w.Header().Set(requestid.Header, id)
+		r = r.WithContext(requestid.NewContext(r.Context(), id))
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.statusCode()
+		attrs := []any{
+			"requestId", id,
+			"method", methodOf(r),
+			"route", routeOf(mux, r),
+			"status", status,
+			"latency", time.Since(start),
+		}
+		// Path values are set by mux, they are missing for replayed responses.
+		for _, name := range []string{"tableId", "playerId"} {
+			if value := r.PathValue(name); value != "" {
+				attrs = append(attrs, name, value)
+			}
+		}
+		level := slog.LevelInfo
+		switch {
+		case status == http.StatusServiceUnavailable:
+			// Only TABLE_UNAVAILABLE problems have this status: a busy table or a server
+			// that is shutting down. Neither is a bug.
+			level = slog.LevelWarn
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		}
+		slog.Log(r.Context(), level, "Served request", attrs...)
+	})
+}
+
+// Recovered turns a panic in next into a 500 response and logs it with its stack trace,
+// so that a bug hit by one request does not take down the server.
+func Recovered(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// Aborting a response on purpose is left to net/http.
+			if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(p)
+			}
+			slog.Error(fmt.Sprintf("Panic serving %v %v: %v", r.Method, r.URL.Path, p),
+				"requestId", requestid.FromContext(r.Context()), "stack", string(debug.Stack()))
+			WriteProblem(w, r, http.StatusInternalServerError, blackjack.CodeInternal, "Internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
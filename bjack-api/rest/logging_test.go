@@ -0,0 +1,171 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/requestid"
+	"github.com/GRO4T/bjack-api/rest"
+)
+
+// captureLogs sends the default logger to a buffer until the end of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &logs
+}
+
+func newLoggedServer(t *testing.T, api *rest.RestApi) *httptest.Server {
+	t.Helper()
+	mux := api.Handler()
+	server := httptest.NewServer(rest.Logged(mux, rest.Recovered(mux)))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLoggedRequest(t *testing.T) {
+	// Arrange
+	logs := captureLogs(t)
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	server := newLoggedServer(t, api)
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/v1/tables/"+testTableId, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(requestid.Header, "abc-123")
+
+	// Act
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// Assert
+	if id := resp.Header.Get(requestid.Header); id != "abc-123" {
+		t.Errorf("Expected the request id to be echoed; got %v", id)
+	}
+	for _, attr := range []string{
+		"requestId=abc-123", "method=GET", "route=/v1/tables/{tableId}", "status=200", "tableId=" + testTableId,
+	} {
+		if !strings.Contains(logs.String(), attr) {
+			t.Errorf("Expected %v in the logs; got %v", attr, logs.String())
+		}
+	}
+}
+
+func TestLoggedRequestWithInvalidId(t *testing.T) {
+	// Arrange
+	captureLogs(t)
+	server := newLoggedServer(t, newTestApi())
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/v1/tables/"+testTableId, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(requestid.Header, "forged\tid")
+
+	// Act
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// Assert
+	if id := resp.Header.Get(requestid.Header); !requestid.Valid(id) || id == "forged\tid" {
+		t.Errorf("Expected a new request id; got %q", id)
+	}
+}
+
+func TestPanicInGameRecovered(t *testing.T) {
+	// Arrange
+	logs := captureLogs(t)
+	api := newTestApi()
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	game.Deck = nil
+	api.Tables.Put(testTableId, &game)
+	server := newLoggedServer(t, api)
+	request, err := http.NewRequest(http.MethodPost,
+		server.URL+"/v1/tables/"+testTableId+"/players/"+newPlayer.Id+"/actions", strings.NewReader(`{"action": "hit"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setSessionToken(t, api, request, testTableId, newPlayer.Id)
+
+	// Act
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500; got %v", resp.Status)
+	}
+	if problem := readProblem(t, resp); problem.Code != blackjack.CodeInternal {
+		t.Errorf("Expected %v; got %+v", blackjack.CodeInternal, problem)
+	}
+	if !strings.Contains(logs.String(), "stack=") {
+		t.Errorf("Expected the stack trace in the logs; got %v", logs.String())
+	}
+}
+
+func TestPanicInHandlerRecovered(t *testing.T) {
+	// Arrange
+	logs := captureLogs(t)
+	handler := rest.Recovered(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("bug")
+	}))
+	recorder := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500; got %v", recorder.Code)
+	}
+	if !strings.Contains(logs.String(), "bug") || !strings.Contains(logs.String(), "stack=") {
+		t.Errorf("Expected the panic to be logged with its stack trace; got %v", logs.String())
+	}
+}
+
+func TestLoggedUnavailableAsWarning(t *testing.T) {
+	// Arrange
+	logs := captureLogs(t)
+	api := newTestApi()
+	api.Tables.Drain()
+	server := newLoggedServer(t, api)
+
+	// Act
+	resp, err := http.Get(server.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503; got %v", resp.Status)
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="Served request"`) {
+		t.Errorf("Expected the request to be logged as a warning; got %v", logs.String())
+	}
+}
@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// unmatchedRoute labels requests that did not match any route, so that
+// scanners probing random paths do not create a series per path.
+const unmatchedRoute = "unmatched"
+
+// Instrument records the latency of every request served by next by method, route and status
+// in a histogram registered with reg. The route is the pattern of mux matching the request,
+// next usually wraps mux.
+func Instrument(reg prometheus.Registerer, mux *http.ServeMux, next http.Handler) http.Handler {
+	latency := promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bjack_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		latency.WithLabelValues(methodOf(r), routeOf(mux, r), strconv.Itoa(recorder.statusCode())).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+// ServeMetrics serves the metrics in the Prometheus text format, see WithMetrics.
+func (a *RestApi) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	if a.metrics == nil {
+		WriteProblem(w, r, http.StatusNotFound, "", "Metrics are not enabled")
+		return
+	}
+	a.metrics.ServeHTTP(w, r)
+}
+
+// methodOf returns the method of the request, or "other" for methods the API does not use.
+func methodOf(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return r.Method
+	}
+	return "other"
+}
+
+// routeOf returns the path of the pattern of mux matching the request.
+func routeOf(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
+
+// statusRecorder remembers the status of the response. Websockets are
+// recorded as 101 Switching Protocols once the connection is hijacked.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b) //nolint: wrapcheck
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking", r.ResponseWriter)
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack() //nolint: wrapcheck
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer, e.g. for flushing events.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,76 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/rest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestInstrumentRecordsRoute(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	reg := prometheus.NewRegistry()
+	mux := api.Handler()
+	server := httptest.NewServer(rest.Instrument(reg, mux, mux))
+	t.Cleanup(server.Close)
+
+	// Act
+	for _, path := range []string{"/v1/tables/" + testTableId, "/no/such/route"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// Assert
+	out := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(out, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, series := range []string{
+		`bjack_http_request_duration_seconds_count{method="GET",route="/v1/tables/{tableId}",status="200"} 1`,
+		`bjack_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(out.Body.String(), series) {
+			t.Errorf("Expected %v in\n%v", series, out.Body.String())
+		}
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	// Arrange
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test counter."}))
+	api := newTestApi(rest.WithMetrics(promhttp.HandlerFor(reg, promhttp.HandlerOpts{})))
+	recorder := httptest.NewRecorder()
+
+	// Act
+	api.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "test_total 0") {
+		t.Errorf("Expected the metrics; got %v %v", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServeMetricsNotEnabled(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	recorder := httptest.NewRecorder()
+
+	// Act
+	api.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404; got %v", recorder.Code)
+	}
+}
@@ -0,0 +1,500 @@
+package rest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+)
+
+// OpenApi is the subset of an OpenAPI 3 document used to describe this API.
+type OpenApi struct {
+	OpenApi    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem maps lower case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+const (
+	jsonContent = "application/json"
+)
+
+//go:embed swagger.html
+var swaggerPage []byte
+
+// swaggerAssets are the files of swagger-ui-dist loaded by swaggerPage.
+//
+//go:embed swagger-ui/*.css swagger-ui/*.js
+var swaggerAssets embed.FS
+
+var (
+	specOnce sync.Once
+	spec     []byte
+)
+
+// Spec returns the OpenAPI document of the routes registered by Handler and of the REST
+// mapping of the gRPC API. Request and response schemas are derived from the Go types.
+func Spec() *OpenApi {
+	s := schemas{}
+	createGame := &Operation{
+		OperationId: "createGame",
+		Summary:     "Create a new table",
+		Parameters:  []Parameter{idempotencyKeyParam},
+		RequestBody: s.body(CreateGameRequest{}),
+		Responses: withErrors(map[string]Response{
+			"200": s.response("The table was created", CreateGameResponse{}),
+		}, "400", "422"),
+	}
+	getGameState := &Operation{
+		OperationId: "getGameState",
+		Summary:     "Get the state of the game",
+		Parameters:  []Parameter{tableIdParam},
+		Responses: withErrors(map[string]Response{
+			"200": s.response("The state of the game", blackjack.Blackjack{}).withHeader("ETag", Header{
+				Description: "Version of the table, to be sent back in If-Match.",
+				Schema:      &Schema{Type: "string"},
+			}),
+		}, "400", "404", "503"),
+	}
+	addPlayer := &Operation{
+		OperationId: "addPlayer",
+		Summary:     "Join the table",
+		Description: "The returned session token authorizes further requests on behalf of the player.",
+		Parameters:  []Parameter{tableIdParam, ifMatchParam, idempotencyKeyParam},
+		RequestBody: s.body(AddPlayerRequest{}),
+		Responses: withErrors(map[string]Response{
+			"200": s.response("The player joined the table", AddPlayerResponse{}),
+		}, "400", "404", "409", "412", "422", "503"),
+	}
+	removePlayer := &Operation{
+		OperationId: "removePlayer",
+		Summary:     "Leave the table",
+		Parameters:  []Parameter{tableIdParam, playerIdParam, ifMatchParam, idempotencyKeyParam},
+		Responses: withErrors(map[string]Response{
+			"200": {Description: "The player left the table"},
+		}, "400", "401", "403", "404", "409", "412", "422", "503"),
+		Security: bearerAuth,
+	}
+	streamEvents := &Operation{
+		OperationId: "streamEvents",
+		Summary:     "Stream the events of the table",
+		Description: "Server-Sent Events. Every event carries its sequence number as the id, " +
+			"the event type as the name and a StateMessage as the data. The stream starts with " +
+			"a Snapshot unless Last-Event-ID names an event whose successors are still kept.",
+		Parameters: []Parameter{tableIdParam, {
+			Name:        "Last-Event-ID",
+			In:          "header",
+			Description: "Sequence number of the last event seen by the client.",
+			Schema:      &Schema{Type: "integer", Format: "uint64"},
+		}},
+		Responses: withErrors(map[string]Response{
+			"200": {
+				Description: "A stream of StateMessage events",
+				Content: map[string]MediaType{
+					"text/event-stream": {Schema: s.of(reflect.TypeFor[StateMessage]())},
+				},
+			},
+		}, "400", "404", "503"),
+	}
+	addStateObserver := &Operation{
+		OperationId: "addStateObserver",
+		Summary:     "Open a websocket to watch and play the game",
+		Description: "The server sends StateMessage and Reply messages, the client sends Command messages. " +
+			"The stream starts with a Snapshot unless since names a message whose successors are still kept. " +
+			"While the cards are dealt, the hands of other players and the dealer's hole card are sent " +
+			"face down as cards with rank and suit 0.",
+		Parameters: []Parameter{tableIdParam, {
+			Name:        "token",
+			In:          "query",
+			Description: "Session token binding the connection to a player. May be sent as a bearer token instead.",
+			Schema:      &Schema{Type: "string"},
+		}, {
+			Name:        "since",
+			In:          "query",
+			Description: "Sequence number of the last message seen by the client.",
+			Schema:      &Schema{Type: "integer", Format: "uint64"},
+		}},
+		Responses: withErrors(map[string]Response{
+			"101": {
+				Description: "Switched to the websocket protocol",
+				Content: map[string]MediaType{
+					jsonContent: {Schema: &Schema{
+						Description: "Messages exchanged over the websocket",
+						Type:        "object",
+						Properties: map[string]*Schema{
+							"server": s.of(reflect.TypeFor[StateMessage]()),
+							"reply":  s.of(reflect.TypeFor[Reply]()),
+							"client": s.of(reflect.TypeFor[Command]()),
+						},
+					}},
+				},
+			},
+		}, "400", "401", "403", "404", "503"),
+	}
+	actionErrors := []string{"400", "401", "403", "404", "409", "412", "422", "503"}
+
+	doc := &OpenApi{
+		OpenApi: "3.0.3",
+		Info:    Info{Title: "Blackjack REST API", Version: "1.0.0"},
+		Paths: map[string]PathItem{
+			"/v1/tables":                              {"post": createGame},
+			"/v1/tables/{tableId}":                    {"get": getGameState},
+			"/v1/tables/{tableId}/players":            {"post": addPlayer},
+			"/v1/tables/{tableId}/events":             {"get": streamEvents},
+			"/v1/tables/{tableId}/updates":            {"get": addStateObserver},
+			"/v1/tables/{tableId}/players/{playerId}": {"delete": removePlayer},
+			"/healthz": {
+				"get": {
+					OperationId: "healthz",
+					Summary:     "Liveness probe",
+					Responses: map[string]Response{
+						"200": s.response("The server is alive", HealthResponse{}),
+					},
+				},
+			},
+			"/metrics": {
+				"get": {
+					OperationId: "metrics",
+					Summary:     "Prometheus metrics",
+					Responses: map[string]Response{
+						"200": {
+							Description: "The metrics in the Prometheus text format",
+							Content:     map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+						},
+					},
+				},
+			},
+			"/readyz": {
+				"get": {
+					OperationId: "readyz",
+					Summary:     "Readiness probe",
+					Description: "The server is not ready while it is shutting down or the data directory is unavailable.",
+					Responses: withErrors(map[string]Response{
+						"200": s.response("The server is ready", HealthResponse{}),
+					}, "503"),
+				},
+			},
+			"/v1/tables/{tableId}/players/{playerId}/ready": {
+				"put": {
+					OperationId: "setPlayerReady",
+					Summary:     "Make the player ready or not ready",
+					Description: "Cards are dealt once all players are ready.",
+					Parameters:  []Parameter{tableIdParam, playerIdParam, ifMatchParam, idempotencyKeyParam},
+					RequestBody: s.body(SetPlayerReadyRequest{}),
+					Responses: withErrors(map[string]Response{
+						"200": s.response("The player after the change", blackjack.Player{}),
+					}, actionErrors...),
+					Security: bearerAuth,
+				},
+			},
+			"/v1/tables/{tableId}/players/{playerId}/actions": {
+				"post": {
+					OperationId: "playerAction",
+					Summary:     "Hit or stand",
+					Parameters:  []Parameter{tableIdParam, playerIdParam, ifMatchParam, idempotencyKeyParam},
+					RequestBody: s.body(PlayerActionRequest{}),
+					Responses: withErrors(map[string]Response{
+						"200": {Description: "The action was taken"},
+					}, actionErrors...),
+					Security: bearerAuth,
+				},
+			},
+
+			"/tables":                              {"post": deprecatedOperation(createGame)},
+			"/tables/{tableId}":                    {"get": deprecatedOperation(getGameState)},
+			"/tables/players/{tableId}":            {"post": deprecatedOperation(addPlayer)},
+			"/tables/players/{tableId}/{playerId}": {"delete": deprecatedOperation(removePlayer)},
+			"/tables/{tableId}/events":             {"get": deprecatedOperation(streamEvents)},
+			"/state-updates/{tableId}":             {"get": deprecatedOperation(addStateObserver)},
+			"/tables/ready/{tableId}/{playerId}": {
+				"post": deprecatedOperation(&Operation{
+					OperationId: "togglePlayerReady",
+					Summary:     "Toggle the readiness of the player",
+					Parameters:  []Parameter{tableIdParam, playerIdParam, ifMatchParam, idempotencyKeyParam},
+					Responses: withErrors(map[string]Response{
+						"200": s.response("The player after the change", blackjack.Player{}),
+					}, actionErrors...),
+					Security: bearerAuth,
+				}),
+			},
+			"/tables/{tableId}/{playerId}": {
+				"post": deprecatedOperation(&Operation{
+					OperationId: "playerAction",
+					Summary:     "Hit or stand",
+					Parameters: []Parameter{tableIdParam, playerIdParam, ifMatchParam, idempotencyKeyParam, {
+						Name:     "action",
+						In:       "query",
+						Required: true,
+						Schema:   &Schema{Type: "string", Enum: actions},
+					}},
+					Responses: withErrors(map[string]Response{
+						"200": {Description: "The action was taken"},
+					}, actionErrors...),
+					Security: bearerAuth,
+				}),
+			},
+		},
+	}
+	maps.Copy(doc.Paths, gatewayPaths(s))
+	s["PlayerActionRequest"].Properties["action"].Enum = actions
+	s.of(reflect.TypeFor[Problem]())
+	doc.Components = Components{
+		Schemas: s,
+		SecuritySchemes: map[string]SecurityScheme{
+			"bearerAuth": {Type: "http", Scheme: "bearer"},
+		},
+	}
+	return doc
+}
+
+// deprecatedOperation returns a copy of op for a deprecated alias of its route.
+// Operation ids must be unique, so the copy gets a suffix.
+func deprecatedOperation(op *Operation) *Operation {
+	alias := *op
+	alias.OperationId += "Deprecated"
+	alias.Deprecated = true
+	return &alias
+}
+
+var (
+	tableIdParam = Parameter{Name: "tableId", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+
+	playerIdParam = Parameter{Name: "playerId", In: "path", Required: true, Schema: &Schema{Type: "string"}}
+
+	ifMatchParam = Parameter{
+		Name:        "If-Match",
+		In:          "header",
+		Description: "ETag of the table. The request fails unless the table is still at that version.",
+		Schema:      &Schema{Type: "string"},
+	}
+
+	idempotencyKeyParam = Parameter{
+		Name: "Idempotency-Key",
+		In:   "header",
+		Description: "Unique key of the request. A retry with the same key gets the response of the first request, " +
+			"marked with an Idempotent-Replayed header, instead of being executed again.",
+		Schema: &Schema{Type: "string"},
+	}
+
+	bearerAuth = []map[string][]string{{"bearerAuth": {}}}
+
+	actions = []string{"hit", "stand"}
+
+	errorDescriptions = map[string]string{
+		"400": "Invalid request",
+		"401": "Missing or invalid session token",
+		"403": "The session token belongs to another player",
+		"404": "Table or player not found",
+		"409": "The state of the game does not allow the request",
+		"412": "The table is no longer at the version from If-Match",
+		"422": "The idempotency key was already used for a different request",
+		"500": "Internal server error",
+		"503": "Table unavailable",
+	}
+)
+
+// withHeader returns a copy of the response with the header added.
+func (r Response) withHeader(name string, header Header) Response {
+	r.Headers = map[string]Header{name: header}
+	return r
+}
+
+// withErrors adds problem details responses with the given status codes.
+func withErrors(responses map[string]Response, codes ...string) map[string]Response {
+	for _, code := range codes {
+		responses[code] = Response{
+			Description: errorDescriptions[code],
+			Content:     map[string]MediaType{problemContent: {Schema: &Schema{Ref: "#/components/schemas/Problem"}}},
+		}
+	}
+	return responses
+}
+
+// schemas collects the schemas of named types as they are referenced.
+type schemas map[string]*Schema
+
+func (s schemas) body(v any) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{jsonContent: {Schema: s.of(reflect.TypeOf(v))}},
+	}
+}
+
+func (s schemas) response(description string, v any) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{jsonContent: {Schema: s.of(reflect.TypeOf(v))}},
+	}
+}
+
+// of returns the schema of values of type t encoded with encoding/json.
+// Structs are added to the components and referenced.
+// nolint: cyclop
+func (s schemas) of(t reflect.Type) *Schema {
+	if t == reflect.TypeFor[blackjack.Code]() {
+		enum := make([]string, len(blackjack.Codes))
+		for i, code := range blackjack.Codes {
+			enum[i] = string(code)
+		}
+		return &Schema{Type: "string", Enum: enum}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.of(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "uint64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Struct:
+		ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+		if _, ok := s[t.Name()]; ok {
+			return ref
+		}
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		// Registered before the fields so that recursive types terminate.
+		s[t.Name()] = schema
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, omitempty := jsonName(field)
+			if name == "" {
+				continue
+			}
+			schema.Properties[name] = s.of(field.Type)
+			if !omitempty && field.Type.Kind() != reflect.Pointer {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+		return ref
+	default:
+		return &Schema{Type: "object"}
+	}
+}
+
+// jsonName returns the name of the field in JSON or an empty string if the field is not encoded.
+func jsonName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, slices.Contains(strings.Split(options, ","), "omitempty")
+}
+
+// ServeSpec serves the OpenAPI document.
+func (a *RestApi) ServeSpec(w http.ResponseWriter, r *http.Request) {
+	specOnce.Do(func() {
+		var err error
+		spec, err = json.Marshal(Spec())
+		if err != nil {
+			panic(fmt.Sprintf("failed to encode the OpenAPI document: %v", err))
+		}
+	})
+	w.Header().Set("Content-Type", jsonContent)
+	if _, err := w.Write(spec); err != nil {
+		slog.Error(fmt.Sprintf("Failed to write response: %v", err))
+	}
+}
+
+// ServeDocs serves a Swagger UI page that renders the OpenAPI document.
+func (a *RestApi) ServeDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(swaggerPage); err != nil {
+		slog.Error(fmt.Sprintf("Failed to write response: %v", err))
+	}
+}
+
+// ServeDocsAsset serves the scripts and styles of the Swagger UI page.
+func (a *RestApi) ServeDocsAsset(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, swaggerAssets, path.Join("swagger-ui", r.PathValue("file")))
+}
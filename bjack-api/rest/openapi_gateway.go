@@ -0,0 +1,191 @@
+package rest
+
+import (
+	pb "github.com/GRO4T/bjack-api/proto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// gatewayPaths describes the REST mapping of the gRPC API served under /api, as declared
+// by the HTTP annotations in blackjack.proto. Bodies are encoded with protojson, so their
+// schemas are derived from the message descriptors rather than the Go types.
+// The routes under /v1 remain the canonical REST API, see the README.
+func gatewayPaths(s schemas) map[string]PathItem {
+	expectedVersionParam := Parameter{
+		Name:        "expectedVersion",
+		In:          "query",
+		Description: "Version of the table. The request fails unless the table is still at that version.",
+		Schema:      &Schema{Type: "string", Format: "uint64"},
+	}
+	actionErrors := []string{"400", "401", "403", "404", "409", "412", "422", "503"}
+	player := s.protoResponse("The player after the change", &pb.Player{})
+
+	return map[string]PathItem{
+		"/api/tables": {
+			"post": {
+				OperationId: "apiCreateGame",
+				Summary:     "Create a new table",
+				Parameters:  []Parameter{idempotencyKeyParam},
+				Responses: withErrors(map[string]Response{
+					"200": s.protoResponse("The table was created", &pb.CreateGameResponse{}),
+				}, "422", "503"),
+			},
+		},
+		"/api/tables/{tableId}": {
+			"get": {
+				OperationId: "apiGetGameState",
+				Summary:     "Get the state of the game",
+				Parameters:  []Parameter{tableIdParam},
+				Responses: withErrors(map[string]Response{
+					"200": s.protoResponse("The state of the game", &pb.GetGameStateResponse{}).withHeader("ETag",
+						Header{Description: "Version of the table.", Schema: &Schema{Type: "string"}}),
+				}, "400", "404", "503"),
+			},
+		},
+		"/api/tables/{tableId}/players": {
+			"post": {
+				OperationId: "apiAddPlayer",
+				Summary:     "Join the table",
+				Description: "The returned session token authorizes further requests on behalf of the player.",
+				Parameters:  []Parameter{tableIdParam, idempotencyKeyParam},
+				RequestBody: s.protoBody(&pb.AddPlayerRequest{}),
+				Responses: withErrors(map[string]Response{
+					"200": s.protoResponse("The player joined the table", &pb.AddPlayerResponse{}),
+				}, "400", "404", "409", "412", "422", "503"),
+			},
+		},
+		"/api/tables/{tableId}/players/{playerId}": {
+			"get": {
+				OperationId: "apiGetPlayer",
+				Summary:     "Get the player",
+				Parameters:  []Parameter{tableIdParam, playerIdParam},
+				Responses: withErrors(map[string]Response{
+					"200": s.protoResponse("The player", &pb.Player{}),
+				}, "400", "401", "403", "404", "503"),
+				Security: bearerAuth,
+			},
+			"delete": {
+				OperationId: "apiRemovePlayer",
+				Summary:     "Leave the table",
+				Parameters:  []Parameter{tableIdParam, playerIdParam, expectedVersionParam, idempotencyKeyParam},
+				Responses: withErrors(map[string]Response{
+					"200": s.protoResponse("The player left the table", &emptypb.Empty{}),
+				}, actionErrors...),
+				Security: bearerAuth,
+			},
+		},
+		"/api/tables/{tableId}/players/{playerId}/ready": {
+			"post": {
+				OperationId: "apiTogglePlayerReady",
+				Summary:     "Toggle the readiness of the player",
+				Parameters:  []Parameter{tableIdParam, playerIdParam, expectedVersionParam, idempotencyKeyParam},
+				Responses:   withErrors(map[string]Response{"200": player}, actionErrors...),
+				Security:    bearerAuth,
+			},
+			"put": {
+				OperationId: "apiSetPlayerReady",
+				Summary:     "Make the player ready or not ready",
+				Description: "Cards are dealt once all players are ready.",
+				Parameters:  []Parameter{tableIdParam, playerIdParam, idempotencyKeyParam},
+				RequestBody: s.protoBody(&pb.SetPlayerReadyRequest{}),
+				Responses:   withErrors(map[string]Response{"200": player}, actionErrors...),
+				Security:    bearerAuth,
+			},
+		},
+		"/api/tables/{tableId}/players/{playerId}/bet": {
+			"post": {
+				OperationId: "apiPlaceBet",
+				Summary:     "Place a bet for the next round",
+				Parameters:  []Parameter{tableIdParam, playerIdParam, idempotencyKeyParam},
+				RequestBody: s.protoBody(&pb.PlaceBetRequest{}),
+				Responses:   withErrors(map[string]Response{"200": player}, actionErrors...),
+				Security:    bearerAuth,
+			},
+		},
+		"/api/tables/{tableId}/players/{playerId}/actions": {
+			"post": {
+				OperationId: "apiPlayerAction",
+				Summary:     "Hit or stand",
+				Parameters:  []Parameter{tableIdParam, playerIdParam, idempotencyKeyParam},
+				RequestBody: s.protoBody(&pb.PlayerActionRequest{}),
+				Responses: withErrors(map[string]Response{
+					"200": s.protoResponse("The action was taken", &emptypb.Empty{}),
+				}, actionErrors...),
+				Security: bearerAuth,
+			},
+		},
+	}
+}
+
+func (s schemas) protoBody(m proto.Message) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{jsonContent: {Schema: s.ofMessage(m.ProtoReflect().Descriptor())}},
+	}
+}
+
+func (s schemas) protoResponse(description string, m proto.Message) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{jsonContent: {Schema: s.ofMessage(m.ProtoReflect().Descriptor())}},
+	}
+}
+
+// ofMessage returns the schema of the message encoded with protojson. Messages are added
+// to the components under their full proto name, so that they do not clash with the Go types.
+func (s schemas) ofMessage(desc protoreflect.MessageDescriptor) *Schema {
+	name := string(desc.FullName())
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := s[name]; ok {
+		return ref
+	}
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	// Registered before the fields so that recursive messages terminate.
+	s[name] = schema
+	fields := desc.Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		fieldSchema := s.ofField(field)
+		if field.IsList() {
+			fieldSchema = &Schema{Type: "array", Items: fieldSchema}
+		}
+		schema.Properties[field.JSONName()] = fieldSchema
+	}
+	return ref
+}
+
+// ofField returns the schema of a single value of the field. protojson writes
+// enums by name and 64-bit integers as strings.
+// nolint: exhaustive
+func (s schemas) ofField(field protoreflect.FieldDescriptor) *Schema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		enum := make([]string, values.Len())
+		for i := range values.Len() {
+			enum[i] = string(values.Get(i).Name())
+		}
+		return &Schema{Type: "string", Enum: enum}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{Type: "integer", Format: "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return &Schema{Type: "number"}
+	case protoreflect.StringKind:
+		return &Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", Format: "byte"}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return s.ofMessage(field.Message())
+	default:
+		return &Schema{Type: "object"}
+	}
+}
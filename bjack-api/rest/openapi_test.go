@@ -0,0 +1,198 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/rest"
+)
+
+func TestSpecDescribesEveryRoute(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	spec := rest.Spec()
+
+	for _, pattern := range api.Patterns() {
+		// Act
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, path = "", pattern
+		}
+		item, found := spec.Paths[path]
+
+		// Assert
+		if !found || len(item) == 0 {
+			t.Errorf("Route %q is missing from the OpenAPI document", pattern)
+			continue
+		}
+		if method != "" && item[strings.ToLower(method)] == nil {
+			t.Errorf("Operation %q is missing from the OpenAPI document", pattern)
+		}
+	}
+}
+
+func TestSpecReferencesResolve(t *testing.T) {
+	// Arrange
+	data, err := json.Marshal(rest.Spec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	schemas := doc.(map[string]any)["components"].(map[string]any)["schemas"].(map[string]any)
+
+	// Act
+	var refs []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				refs = append(refs, ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+
+	// Assert
+	if len(refs) == 0 {
+		t.Fatal("Expected schemas to be referenced")
+	}
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if !ok || schemas[name] == nil {
+			t.Errorf("Unresolved reference %q", ref)
+		}
+	}
+}
+
+func TestSpecSchemasFollowJsonTags(t *testing.T) {
+	// Arrange
+	spec := rest.Spec()
+
+	// Act
+	player := spec.Components.Schemas["Player"]
+	game := spec.Components.Schemas["Blackjack"]
+
+	// Assert
+	if player == nil || game == nil {
+		t.Fatalf("Expected Player and Blackjack schemas; got %v", spec.Components.Schemas)
+	}
+	if _, ok := player.Properties["isReady"]; !ok {
+		t.Errorf("Expected isReady property; got %v", player.Properties)
+	}
+	if _, ok := player.Properties["Id"]; ok {
+		t.Error("Expected fields excluded from JSON to be left out")
+	}
+	if _, ok := game.Properties["Deck"]; ok {
+		t.Error("Expected the deck to be left out")
+	}
+	if hands := game.Properties["hands"]; hands == nil || hands.Type != "array" || hands.Items.Type != "array" {
+		t.Errorf("Expected hands to be an array of arrays; got %+v", hands)
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	// Act
+	resp, err := http.Get(server.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var spec rest.OpenApi
+	err = json.NewDecoder(resp.Body).Decode(&spec)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON document; got %v %v", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	if !strings.HasPrefix(spec.OpenApi, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document; got %q", spec.OpenApi)
+	}
+	if spec.Paths["/tables"]["post"] == nil {
+		t.Error("Expected the document to describe POST /tables")
+	}
+}
+
+func TestServeDocs(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	// Act
+	resp, err := http.Get(server.URL + "/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	page, err := io.ReadAll(resp.Body)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Expected an HTML page; got %v %v", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(page), "/openapi.json") {
+		t.Error("Expected the page to load the OpenAPI document")
+	}
+	if !strings.Contains(string(page), `src="/docs/swagger-ui-bundle.js"`) {
+		t.Error("Expected the page to load Swagger UI from the server")
+	}
+}
+
+func TestServeDocsAssets(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	for path, contentType := range map[string]string{
+		"/docs/swagger-ui.css":       "text/css",
+		"/docs/swagger-ui-bundle.js": "text/javascript",
+		"/docs/README.md":            "",
+	} {
+		// Act
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		// Assert
+		if contentType == "" {
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected %v to be missing; got %v", path, resp.Status)
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), contentType) {
+			t.Errorf("Expected %v to be served as %v; got %v %v",
+				path, contentType, resp.Status, resp.Header.Get("Content-Type"))
+		}
+	}
+}
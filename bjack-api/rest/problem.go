@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/requestid"
+)
+
+const problemContent = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code is the code of the error
+// from the blackjack catalog, so that clients can tell errors apart without parsing Detail.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     blackjack.Code `json:"code,omitempty"`
+}
+
+var httpStatuses = map[blackjack.Code]int{
+	blackjack.CodeInvalidArgument:      http.StatusBadRequest,
+	blackjack.CodeUnauthenticated:      http.StatusUnauthorized,
+	blackjack.CodePermissionDenied:     http.StatusForbidden,
+	blackjack.CodeTableNotFound:        http.StatusNotFound,
+	blackjack.CodeTableUnavailable:     http.StatusServiceUnavailable,
+	blackjack.CodePlayerNotFound:       http.StatusNotFound,
+	blackjack.CodeNameTaken:            http.StatusConflict,
+	blackjack.CodeGameIsFull:           http.StatusConflict,
+	blackjack.CodeGameAlreadyStarted:   http.StatusConflict,
+	blackjack.CodeCardsAlreadyDealt:    http.StatusConflict,
+	blackjack.CodeGameNotInProgress:    http.StatusConflict,
+	blackjack.CodeOtherPlayerTurn:      http.StatusConflict,
+	blackjack.CodeInvalidBet:           http.StatusBadRequest,
+	blackjack.CodeVersionMismatch:      http.StatusPreconditionFailed,
+	blackjack.CodeIdempotencyKeyReused: http.StatusUnprocessableEntity,
+	blackjack.CodeInternal:             http.StatusInternalServerError,
+}
+
+// WriteProblem writes a problem details response. It is exported for the other HTTP
+// surfaces of the server, so that all of them report errors alike.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, code blackjack.Code, detail string) {
+	w.Header().Set("Content-Type", problemContent)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.Error(fmt.Sprintf("Failed to write response: %v", err))
+	}
+}
+
+// writeError writes a problem details response with the status matching the code of err.
+// A table that does not reply in time is reported as unavailable. Errors without a code
+// are logged and reported as internal errors without revealing the cause.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		WriteProblem(w, r, http.StatusServiceUnavailable, blackjack.CodeTableUnavailable, "Table unavailable")
+		return
+	}
+	var e *blackjack.Error
+	if !errors.As(err, &e) {
+		slog.Error(err.Error(), "requestId", requestid.FromContext(r.Context()))
+		WriteProblem(w, r, http.StatusInternalServerError, blackjack.CodeInternal, "Internal server error")
+		return
+	}
+	status, ok := HttpStatus(e.Code)
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	WriteProblem(w, r, status, e.Code, e.Message)
+}
+
+// HttpStatus returns the status of problem responses for errors with the code.
+func HttpStatus(code blackjack.Code) (int, bool) {
+	status, ok := httpStatuses[code]
+	return status, ok
+}
+
+// invalidArgument returns an error reported with a 400 status.
+func invalidArgument(format string, args ...any) error {
+	return blackjack.NewError(blackjack.CodeInvalidArgument, format, args...)
+}
@@ -0,0 +1,173 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/rest"
+)
+
+func readProblem(t *testing.T, resp *http.Response) rest.Problem {
+	t.Helper()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/problem+json" {
+		t.Fatalf("Expected a problem details response; got %v", contentType)
+	}
+	var problem rest.Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Status != resp.StatusCode {
+		t.Errorf("Expected status %v in the problem; got %v", resp.StatusCode, problem.Status)
+	}
+	return problem
+}
+
+func TestEveryErrorCodeHasHttpStatus(t *testing.T) {
+	for _, code := range blackjack.Codes {
+		// Act
+		_, ok := rest.HttpStatus(code)
+
+		// Assert
+		if !ok {
+			t.Errorf("Expected an HTTP status for %v", code)
+		}
+	}
+}
+
+func TestCreateGameWithMalformedBody(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	request := httptest.NewRequest(http.MethodPost, "/v1/tables", strings.NewReader("{"))
+	responseWriter := httptest.NewRecorder()
+
+	// Act
+	api.CreateGame(responseWriter, request)
+	resp := responseWriter.Result()
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status 400; got %v", resp.Status)
+	}
+	if problem := readProblem(t, resp); problem.Code != blackjack.CodeInvalidArgument {
+		t.Errorf("Expected %v; got %+v", blackjack.CodeInvalidArgument, problem)
+	}
+}
+
+func TestAddPlayerWithTakenName(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	if _, err := game.AddPlayer("Player 1"); err != nil {
+		t.Fatal(err)
+	}
+	api.Tables.Put(testTableId, &game)
+	responseWriter := httptest.NewRecorder()
+
+	// Act
+	api.AddPlayer(responseWriter, buildAddPlayerRequest(t, testTableId, "Player 1"))
+	resp := responseWriter.Result()
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status 409; got %v", resp.Status)
+	}
+	problem := readProblem(t, resp)
+	if problem.Code != blackjack.CodeNameTaken || !strings.Contains(problem.Detail, "Player 1") {
+		t.Errorf("Expected %v for Player 1; got %+v", blackjack.CodeNameTaken, problem)
+	}
+}
+
+func TestPlayerActionOnOtherPlayerTurn(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	_, _ = game.AddPlayer("Player 1")
+	secondPlayer, _ := game.AddPlayer("Player 2")
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	api.Tables.Put(testTableId, &game)
+	request := httptest.NewRequest(
+		http.MethodPost, "/v1/tables/{tableId}/players/{playerId}/actions", strings.NewReader(`{"action": "hit"}`))
+	request.SetPathValue("tableId", testTableId)
+	request.SetPathValue("playerId", secondPlayer.Id)
+	setSessionToken(t, api, request, testTableId, secondPlayer.Id)
+	responseWriter := httptest.NewRecorder()
+
+	// Act
+	api.PlayerAction(responseWriter, request)
+	resp := responseWriter.Result()
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status 409; got %v", resp.Status)
+	}
+	if problem := readProblem(t, resp); problem.Code != blackjack.CodeOtherPlayerTurn {
+		t.Errorf("Expected %v; got %+v", blackjack.CodeOtherPlayerTurn, problem)
+	}
+}
+
+func TestGetGameStateOfMissingTable(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	request := httptest.NewRequest(http.MethodGet, "/v1/tables/{tableId}", nil)
+	request.SetPathValue("tableId", testTableId)
+	responseWriter := httptest.NewRecorder()
+
+	// Act
+	api.GetGameState(responseWriter, request)
+	resp := responseWriter.Result()
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected status 404; got %v", resp.Status)
+	}
+	problem := readProblem(t, resp)
+	if problem.Code != blackjack.CodeTableNotFound || problem.Type != "about:blank" || problem.Title != "Not Found" {
+		t.Errorf("Expected a %v problem; got %+v", blackjack.CodeTableNotFound, problem)
+	}
+}
+
+func TestErrorReplyHidesErrorsWithoutCode(t *testing.T) {
+	// Act
+	reply := rest.ErrorReply(errors.New("disk on fire"))
+
+	// Assert
+	if reply.Code != blackjack.CodeInternal || reply.Error != "Internal server error" {
+		t.Errorf("Expected a generic internal error; got %+v", reply)
+	}
+}
+
+func TestErrorReplyKeepsCatalogErrors(t *testing.T) {
+	// Act
+	reply := rest.ErrorReply(blackjack.ErrNameTaken)
+
+	// Assert
+	if reply.Code != blackjack.CodeNameTaken || reply.Error != blackjack.ErrNameTaken.Error() {
+		t.Errorf("Expected %v; got %+v", blackjack.ErrNameTaken, reply)
+	}
+}
+
+func TestOnlyTableUnavailableIsServiceUnavailable(t *testing.T) {
+	for _, code := range blackjack.Codes {
+		// Act
+		status, _ := rest.HttpStatus(code)
+
+		// Assert
+		if status == http.StatusServiceUnavailable && code != blackjack.CodeTableUnavailable {
+			t.Errorf("Expected only %v to be 503, as Logged logs 503s as warnings; got %v",
+				blackjack.CodeTableUnavailable, code)
+		}
+	}
+}
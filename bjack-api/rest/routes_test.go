@@ -0,0 +1,175 @@
+// nolint: noctx
+package rest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/GRO4T/bjack-api/rest"
+)
+
+func TestSetPlayerReady(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	_, _ = game.AddPlayer("Player 2")
+	api.Tables.Put(testTableId, &game)
+	var events []registry.Event
+	api.Tables.AddListener(func(event registry.Event) {
+		events = append(events, event)
+	})
+	setReady := func(ready bool) blackjack.Player {
+		t.Helper()
+		body := `{"ready": false}`
+		if ready {
+			body = `{"ready": true}`
+		}
+		request := httptest.NewRequest(http.MethodPut, "/v1/tables/{tableId}/players/{playerId}/ready", strings.NewReader(body))
+		request.SetPathValue("tableId", testTableId)
+		request.SetPathValue("playerId", newPlayer.Id)
+		setSessionToken(t, api, request, testTableId, newPlayer.Id)
+		responseWriter := httptest.NewRecorder()
+		api.SetPlayerReady(responseWriter, request)
+		resp := responseWriter.Result()
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status OK; got %v", resp.Status)
+		}
+		var player blackjack.Player
+		if err := json.NewDecoder(resp.Body).Decode(&player); err != nil {
+			t.Fatal(err)
+		}
+		return player
+	}
+
+	// Act
+	first := setReady(true)
+	second := setReady(true)
+
+	// Assert
+	if !first.IsReady || !second.IsReady {
+		t.Errorf("Expected player to be ready; got %+v and %+v", first, second)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected 1 event; got %v", len(events))
+	}
+}
+
+func TestDeprecatedRoute(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	api.Tables.Put(testTableId, &game)
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	// Act
+	resp, err := http.Post(server.URL+"/tables/players/"+testTableId, "application/json",
+		strings.NewReader(`{"playerName": "Player 1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+	if resp.Header.Get("Deprecation") != "true" {
+		t.Error("Expected the response to be marked as deprecated")
+	}
+	expectedLink := `</v1/tables/` + testTableId + `/players>; rel="successor-version"`
+	if link := resp.Header.Get("Link"); link != expectedLink {
+		t.Errorf("Expected link %v; got %v", expectedLink, link)
+	}
+}
+
+func TestDeprecatedPlayerActionWithQuery(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	game := blackjack.New(nil)
+	newPlayer, _ := game.AddPlayer("Player 1")
+	if err := game.Deal(); err != nil {
+		t.Fatal(err)
+	}
+	game.State = blackjack.CardsDealt
+	api.Tables.Put(testTableId, &game)
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/tables/"+testTableId+"/"+newPlayer.Id+"?action=hit", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setSessionToken(t, api, request, testTableId, newPlayer.Id)
+
+	// Act
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+	if len(game.GetPlayerHand(0)) != 3 {
+		t.Errorf("Expected 3 cards; got %v", len(game.GetPlayerHand(0)))
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+	server := httptest.NewServer(api.Handler())
+	defer server.Close()
+
+	for _, path := range []string{"/v1/tables", "/tables"} {
+		// Act
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		// Assert
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405 for %v; got %v", path, resp.Status)
+		}
+	}
+	if api.Tables.Len() != 0 {
+		t.Errorf("Expected no tables; got %v", api.Tables.Len())
+	}
+}
+
+// operationalPaths are probed by tooling at fixed paths, so they are not versioned.
+var operationalPaths = []string{"/healthz", "/readyz", "/metrics"}
+
+func TestRoutesUseVersionedPaths(t *testing.T) {
+	// Arrange
+	api := newTestApi()
+
+	for _, pattern := range api.Patterns() {
+		// Act
+		method, path, ok := strings.Cut(pattern, " ")
+
+		// Assert
+		if !ok || method == "" {
+			t.Errorf("Expected route %q to have a method", pattern)
+		}
+		if slices.Contains(operationalPaths, path) {
+			continue
+		}
+		deprecated := rest.Spec().Paths[path][strings.ToLower(method)]
+		if !strings.HasPrefix(path, "/v1/") && (deprecated == nil || !deprecated.Deprecated) {
+			t.Errorf("Expected unversioned route %q to be documented as deprecated", pattern)
+		}
+	}
+}
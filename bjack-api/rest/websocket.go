@@ -0,0 +1,294 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GRO4T/bjack-api/auth"
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/constant"
+	"github.com/GRO4T/bjack-api/deck"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsMaxMessageLen = 4096
+	// ReplyMessage is the type of messages that answer a command.
+	ReplyMessage = "Reply"
+)
+
+type CommandType string
+
+const (
+	JoinCommand  CommandType = "join"
+	LeaveCommand CommandType = "leave"
+	ReadyCommand CommandType = "ready"
+	BetCommand   CommandType = "bet"
+	HitCommand   CommandType = "hit"
+	StandCommand CommandType = "stand"
+)
+
+// StateMessage is sent over the state updates websocket. The first message
+// of a connection is a Snapshot, followed by a message after every event.
+type StateMessage struct {
+	Type     registry.EventType `json:"type"`
+	Seq      uint64             `json:"seq"`
+	Version  uint64             `json:"version"`
+	PlayerId string             `json:"playerId,omitempty"`
+	// State of the game right after the event. Not set for TableClosed and ServerShutdown.
+	// Over a websocket, cards the player of the connection may not see are face down, see viewOf.
+	State *blackjack.Blackjack `json:"state,omitempty"`
+}
+
+// Command is sent by the client over the websocket to play the game.
+// Every command gets a Reply with the same id.
+type Command struct {
+	Id         string      `json:"id"`
+	Type       CommandType `json:"type"`
+	PlayerName string      `json:"playerName,omitempty"` // join
+	Amount     int         `json:"amount,omitempty"`     // bet
+}
+
+type Reply struct {
+	Type  string         `json:"type"`
+	Id    string         `json:"id"`
+	Ok    bool           `json:"ok"`
+	Error string         `json:"error,omitempty"`
+	Code  blackjack.Code `json:"code,omitempty"`
+	// Set in reply to join. From then on the connection acts on behalf of the new player.
+	PlayerId string `json:"playerId,omitempty"`
+	Token    string `json:"token,omitempty"`
+	// Set in reply to ready and bet.
+	Player *blackjack.Player `json:"player,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Accepting all requests
+	},
+}
+
+// AddStateObserver upgrades the request to a websocket that streams the state of the table
+// and accepts commands. A session token from the "token" query parameter or the Authorization
+// header binds the connection to a player. Without it the connection can only join the table.
+// The stream starts with a snapshot, unless the "since" query parameter holds the sequence number
+// of the last message the client has seen. Then it starts with the missed events if they are still kept.
+func (a *RestApi) AddStateObserver(w http.ResponseWriter, r *http.Request) {
+	tableId := r.PathValue("tableId")
+
+	if !validateIds(w, r, tableId) {
+		return
+	}
+
+	var playerId string
+	if token := websocketToken(r); token != "" {
+		claims, err := a.Signer.Verify(token)
+		if err != nil {
+			writeError(w, r, authError(err))
+			return
+		}
+		if claims.TableId != tableId {
+			writeError(w, r, authError(auth.ErrTokenMismatch))
+			return
+		}
+		playerId = claims.PlayerId
+	}
+
+	var sinceSeq uint64
+	since := r.URL.Query().Get("since")
+	if since != "" {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			writeError(w, r, invalidArgument("Invalid since"))
+			return
+		}
+		sinceSeq = seq
+	}
+
+	table, err := a.Tables.Get(tableId)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), constant.CommandTimeout)
+	catchUp, events, unsubscribe, err := a.Tables.Watch(ctx, table, since != "", sinceSeq)
+	cancel()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer unsubscribe()
+
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an error.
+		slog.Debug("Failed to upgrade to websocket", "error", err)
+		return
+	}
+	conn := newWsConn(ws, tableId)
+	conn.setPlayer(playerId)
+	a.hub.register(conn)
+	defer a.hub.unregister(conn)
+	slog.Debug("Created a websocket for state updates", "tableId", tableId)
+
+	go conn.writeLoop(catchUp, sinceSeq, events, a.pingInterval)
+	a.readCommands(conn, table, playerId)
+	// The peer is gone or the writer closed the socket.
+	conn.abort()
+}
+
+func websocketToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+func stateMessage(event registry.Event) StateMessage {
+	return StateMessage{
+		Type:     event.Type,
+		Seq:      event.Seq,
+		Version:  event.Version,
+		PlayerId: event.PlayerId,
+		State:    event.Game,
+	}
+}
+
+// viewOf returns the game as seen by the player while the cards are dealt: the hands of
+// other players and the dealer's hole card are face down, sent as zero cards, so that the
+// number of cards stays visible. Connections without a player see no hand but the dealer's
+// up card. Once the round is finished, all cards are shown. The game is not modified.
+func viewOf(game *blackjack.Blackjack, playerId string) *blackjack.Blackjack {
+	if game == nil || game.State != blackjack.CardsDealt {
+		return game
+	}
+	view := *game
+	view.Hands = make([][]deck.Card, len(game.Hands))
+	for i, hand := range game.Hands {
+		// The first hand is the dealer's, followed by the hands of the players in order.
+		own := i > 0 && i <= len(game.Players) && playerId != "" && game.Players[i-1].Id == playerId
+		view.Hands[i] = make([]deck.Card, len(hand))
+		for j, card := range hand {
+			if own || i == 0 && j == 0 {
+				view.Hands[i][j] = card
+			}
+		}
+	}
+	return &view
+}
+
+// readCommands executes commands sent over the websocket until the connection fails
+// or the peer stops answering pings.
+func (a *RestApi) readCommands(conn *wsConn, table *registry.Table, playerId string) {
+	pongWait := 2 * a.pingInterval
+	conn.ws.SetReadLimit(wsMaxMessageLen)
+	extendDeadline := func(string) error {
+		return conn.ws.SetReadDeadline(time.Now().Add(pongWait)) //nolint: wrapcheck
+	}
+	if err := extendDeadline(""); err != nil {
+		return
+	}
+	conn.ws.SetPongHandler(extendDeadline)
+	for {
+		_, data, err := conn.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := extendDeadline(""); err != nil {
+			return
+		}
+		var cmd Command
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			reply := errorReply(blackjack.NewError(blackjack.CodeInvalidArgument, "Invalid command: %v", err))
+			reply.Type = ReplyMessage
+			conn.enqueue(reply)
+			continue
+		}
+		reply := a.executeCommand(table, &playerId, cmd)
+		conn.setPlayer(playerId)
+		reply.Type = ReplyMessage
+		reply.Id = cmd.Id
+		conn.enqueue(reply)
+	}
+}
+
+// executeCommand runs cmd on behalf of the player bound to the connection.
+// A successful join binds the connection to the new player, a leave unbinds it.
+// nolint: cyclop
+func (a *RestApi) executeCommand(table *registry.Table, playerId *string, cmd Command) Reply {
+	ctx, cancel := context.WithTimeout(context.Background(), constant.CommandTimeout)
+	defer cancel()
+
+	if cmd.Type == JoinCommand {
+		if *playerId != "" {
+			return errorReply(blackjack.NewError(blackjack.CodeInvalidArgument, "Already joined"))
+		}
+		if cmd.PlayerName == "" {
+			return errorReply(invalidArgument("Player name cannot be empty"))
+		}
+		player, err := table.Join(ctx, cmd.PlayerName)
+		if err != nil {
+			return errorReply(err)
+		}
+		token, err := a.Signer.Issue(table.Id, player.Id)
+		if err != nil {
+			return errorReply(fmt.Errorf("failed to issue session token: %w", err))
+		}
+		*playerId = player.Id
+		return Reply{Ok: true, PlayerId: player.Id, Token: token}
+	}
+
+	if *playerId == "" {
+		return errorReply(blackjack.NewError(blackjack.CodeUnauthenticated, "Missing session token"))
+	}
+	var player blackjack.Player
+	var err error
+	switch cmd.Type {
+	case LeaveCommand:
+		err = table.Leave(ctx, *playerId)
+		if err == nil {
+			*playerId = ""
+		}
+	case ReadyCommand:
+		player, err = table.ToggleReady(ctx, *playerId)
+	case BetCommand:
+		player, err = table.PlaceBet(ctx, *playerId, cmd.Amount)
+	case HitCommand:
+		err = table.Act(ctx, *playerId, blackjack.Hit)
+	case StandCommand:
+		err = table.Act(ctx, *playerId, blackjack.Stand)
+	default:
+		return errorReply(invalidArgument("Unknown command %q", cmd.Type))
+	}
+	if err != nil {
+		return errorReply(err)
+	}
+	if cmd.Type == ReadyCommand || cmd.Type == BetCommand {
+		return Reply{Ok: true, Player: &player}
+	}
+	return Reply{Ok: true}
+}
+
+// errorReply reports err like writeError: a table that does not reply in time is unavailable
+// and errors without a code are logged and reported as internal errors without the cause.
+func errorReply(err error) Reply {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Reply{Error: "Table unavailable", Code: blackjack.CodeTableUnavailable}
+	}
+	var e *blackjack.Error
+	if !errors.As(err, &e) {
+		slog.Error(err.Error())
+		return Reply{Error: "Internal server error", Code: blackjack.CodeInternal}
+	}
+	return Reply{Error: err.Error(), Code: e.Code}
+}
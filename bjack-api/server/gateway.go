@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"github.com/GRO4T/bjack-api/requestid"
+	"github.com/GRO4T/bjack-api/rest"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// GatewayPrefix is the path under which the REST mapping of the gRPC API is served.
+const GatewayPrefix = "/api/"
+
+// Gateway serves the REST mapping of the gRPC API, as declared by the HTTP
+// annotations in blackjack.proto. Requests and responses are encoded with protojson
+// and calls go straight to srv, without a network round trip. Responses carrying
+// a session token are marked Cache-Control: no-store and the state of the game carries
+// the version of the table as its ETag. Errors are problem details, like those of the
+// hand-written REST API, which stays the canonical one.
+func Gateway(ctx context.Context, srv pb.BlackjackServer) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}),
+		runtime.WithForwardResponseOption(markNoStore),
+		runtime.WithForwardResponseOption(setETag),
+		runtime.WithErrorHandler(writeStatus),
+		runtime.WithRoutingErrorHandler(writeRoutingError),
+	)
+	if err := pb.RegisterBlackjackHandlerServer(ctx, mux, srv); err != nil {
+		return nil, fmt.Errorf("failed to register gateway handlers: %w", err)
+	}
+	return mux, nil
+}
+
+// tokenMessage is implemented by the responses of calls issuing session tokens.
+type tokenMessage interface {
+	GetToken() string
+}
+
+func markNoStore(_ context.Context, w http.ResponseWriter, m proto.Message) error {
+	if m, ok := m.(tokenMessage); ok && m.GetToken() != "" {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	return nil
+}
+
+// setETag sets the ETag of the state of the game to the version of the table, like /v1 does.
+// Unlike /v1, the version is sent back in the expectedVersion field instead of If-Match.
+func setETag(_ context.Context, w http.ResponseWriter, m proto.Message) error {
+	if m, ok := m.(*pb.GetGameStateResponse); ok {
+		w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(m.GetVersion(), 10)))
+	}
+	return nil
+}
+
+// writeStatus writes the gRPC status of a failed call as problem details. The code of
+// the problem is the reason of the ErrorInfo attached by the server. Statuses without it
+// come from the gateway itself, such as a malformed request body.
+func writeStatus(
+	ctx context.Context,
+	_ *runtime.ServeMux,
+	_ runtime.Marshaler,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == bgrpc.ErrorDomain {
+			code := blackjack.Code(info.GetReason())
+			httpStatus, ok := rest.HttpStatus(code)
+			if !ok {
+				httpStatus = http.StatusInternalServerError
+			}
+			rest.WriteProblem(w, r, httpStatus, code, st.Message())
+			return
+		}
+	}
+	switch st.Code() {
+	case codes.InvalidArgument:
+		rest.WriteProblem(w, r, http.StatusBadRequest, blackjack.CodeInvalidArgument, st.Message())
+	case codes.DeadlineExceeded:
+		rest.WriteProblem(w, r, http.StatusServiceUnavailable, blackjack.CodeTableUnavailable, "Table unavailable")
+	case codes.Canceled:
+		// The client is most likely gone and will not read it.
+		rest.WriteProblem(w, r, runtime.HTTPStatusFromCode(codes.Canceled), "", "Request canceled")
+	default:
+		slog.Error(st.Message(), "requestId", requestid.FromContext(ctx))
+		rest.WriteProblem(w, r, http.StatusInternalServerError, blackjack.CodeInternal, "Internal server error")
+	}
+}
+
+// writeRoutingError writes problem details for requests that match no call.
+func writeRoutingError(
+	_ context.Context,
+	_ *runtime.ServeMux,
+	_ runtime.Marshaler,
+	w http.ResponseWriter,
+	r *http.Request,
+	httpStatus int,
+) {
+	rest.WriteProblem(w, r, httpStatus, "", http.StatusText(httpStatus))
+}
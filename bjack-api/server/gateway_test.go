@@ -0,0 +1,261 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/auth"
+	"github.com/GRO4T/bjack-api/blackjack"
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/GRO4T/bjack-api/rest"
+	"github.com/GRO4T/bjack-api/server"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+)
+
+func setupGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	srv := bgrpc.NewServer(auth.NewSigner([]byte("secret"), time.Hour), tables)
+	gateway, err := server.Gateway(context.Background(), srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(gateway)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doJson(t *testing.T, method string, url string, token string, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestGateway_SimpleGame(t *testing.T) {
+	ts := setupGateway(t)
+
+	// Create game
+	var created struct {
+		TableId string `json:"tableId"`
+	}
+	if code := doJson(t, http.MethodPost, ts.URL+"/api/tables", "", "", &created); code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+	tableUrl := ts.URL + "/api/tables/" + created.TableId
+
+	// Add player
+	var added struct {
+		PlayerId string `json:"playerId"`
+		Token    string `json:"token"`
+	}
+	code := doJson(t, http.MethodPost, tableUrl+"/players", "", `{"playerName": "Player 1"}`, &added)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+	playerUrl := tableUrl + "/players/" + added.PlayerId
+
+	// Place bet
+	var player struct {
+		Name    string `json:"name"`
+		IsReady bool   `json:"isReady"`
+		Bet     int    `json:"bet"`
+	}
+	code = doJson(t, http.MethodPost, playerUrl+"/bet", added.Token, `{"amount": 10}`, &player)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+	if player.Bet != 10 {
+		t.Errorf("Expected a bet of 10; got %+v", player)
+	}
+
+	// Set player ready
+	code = doJson(t, http.MethodPut, playerUrl+"/ready", added.Token, `{"ready": true}`, &player)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+	if player.Name != "Player 1" || !player.IsReady {
+		t.Errorf("Expected Player 1 to be ready; got %+v", player)
+	}
+
+	// Player stand
+	code = doJson(t, http.MethodPost, playerUrl+"/actions", added.Token, `{"action": "STAND"}`, nil)
+	if code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+
+	// Get game state
+	var state struct {
+		State string `json:"state"`
+	}
+	if code := doJson(t, http.MethodGet, tableUrl, "", "", &state); code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+	if state.State != "FINISHED" {
+		t.Errorf("Expected FINISHED state; got %v", state.State)
+	}
+}
+
+func TestGateway_ErrorStatus(t *testing.T) {
+	ts := setupGateway(t)
+	var created struct {
+		TableId string `json:"tableId"`
+	}
+	if code := doJson(t, http.MethodPost, ts.URL+"/api/tables", "", "", &created); code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+	playerUrl := ts.URL + "/api/tables/" + created.TableId + "/players/AAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		expected     int
+		expectedCode blackjack.Code
+	}{
+		{"invalid table id", http.MethodGet, ts.URL + "/api/tables/1", "", http.StatusBadRequest,
+			blackjack.CodeInvalidArgument},
+		{"missing table", http.MethodGet, ts.URL + "/api/tables/ABC234", "", http.StatusNotFound,
+			blackjack.CodeTableNotFound},
+		{"missing session token", http.MethodPost, playerUrl + "/ready", "", http.StatusUnauthorized,
+			blackjack.CodeUnauthenticated},
+		{"malformed body", http.MethodPost, ts.URL + "/api/tables/" + created.TableId + "/players", "{",
+			http.StatusBadRequest, blackjack.CodeInvalidArgument},
+		{"unknown path", http.MethodGet, ts.URL + "/api/chairs", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			req, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			// Assert
+			if resp.StatusCode != tt.expected {
+				t.Fatalf("Expected status %v; got %v", tt.expected, resp.Status)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != "application/problem+json" {
+				t.Fatalf("Expected a problem details response; got %v", contentType)
+			}
+			var problem rest.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Status != tt.expected || problem.Code != tt.expectedCode {
+				t.Errorf("Expected status %v and code %q; got %+v", tt.expected, tt.expectedCode, problem)
+			}
+		})
+	}
+}
+
+func TestGateway_SessionTokenNotStored(t *testing.T) {
+	// Arrange
+	ts := setupGateway(t)
+	var created struct {
+		TableId string `json:"tableId"`
+	}
+	if code := doJson(t, http.MethodPost, ts.URL+"/api/tables", "", "", &created); code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+
+	// Act
+	resp, err := http.Post(ts.URL+"/api/tables/"+created.TableId+"/players", "application/json",
+		strings.NewReader(`{"playerName": "Player 1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the session token not to be stored; got %v with Cache-Control %q",
+			resp.Status, resp.Header.Get("Cache-Control"))
+	}
+}
+
+func TestGateway_ETag(t *testing.T) {
+	// Arrange
+	ts := setupGateway(t)
+	var created struct {
+		TableId string `json:"tableId"`
+	}
+	if code := doJson(t, http.MethodPost, ts.URL+"/api/tables", "", "", &created); code != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", code)
+	}
+
+	// Act
+	resp, err := http.Get(ts.URL + "/api/tables/" + created.TableId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Assert
+	if etag := resp.Header.Get("ETag"); etag != `"0"` {
+		t.Errorf(`Expected ETag "0"; got %q`, etag)
+	}
+}
+
+func TestGateway_SpecDescribesEveryCall(t *testing.T) {
+	// Arrange
+	spec := rest.Spec()
+	methods := pb.File_blackjack_proto.Services().ByName("Blackjack").Methods()
+
+	for i := range methods.Len() {
+		// Act
+		rule, ok := proto.GetExtension(methods.Get(i).Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		var method, path string
+		switch pattern := rule.GetPattern().(type) {
+		case *annotations.HttpRule_Get:
+			method, path = "get", pattern.Get
+		case *annotations.HttpRule_Put:
+			method, path = "put", pattern.Put
+		case *annotations.HttpRule_Post:
+			method, path = "post", pattern.Post
+		case *annotations.HttpRule_Delete:
+			method, path = "delete", pattern.Delete
+		case *annotations.HttpRule_Patch:
+			method, path = "patch", pattern.Patch
+		}
+
+		// Assert
+		if spec.Paths[path][method] == nil {
+			t.Errorf("%v %v of %v is missing from the OpenAPI document", method, path, methods.Get(i).Name())
+		}
+	}
+}
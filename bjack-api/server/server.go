@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// Handler serves gRPC calls with grpcServer and all other requests with rest.
+// HTTP/2 without TLS (h2c) is accepted, so both APIs can share a single port.
+func Handler(grpcServer *grpc.Server, rest http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isGrpc(r) {
+			rest.ServeHTTP(w, r)
+			return
+		}
+		// Streaming calls can outlive the timeouts meant for REST requests.
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+		grpcServer.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
+
+func isGrpc(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
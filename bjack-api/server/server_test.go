@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GRO4T/bjack-api/auth"
+	bgrpc "github.com/GRO4T/bjack-api/grpc"
+	pb "github.com/GRO4T/bjack-api/proto"
+	"github.com/GRO4T/bjack-api/registry"
+	"github.com/GRO4T/bjack-api/rest"
+	"github.com/GRO4T/bjack-api/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// nolint: ireturn
+func setup(t *testing.T) (*httptest.Server, pb.BlackjackClient) {
+	t.Helper()
+	tables := registry.New()
+	t.Cleanup(tables.Close)
+	signer := auth.NewSigner([]byte("secret"), time.Hour)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterBlackjackServer(grpcServer, bgrpc.NewServer(signer, tables))
+	ts := httptest.NewServer(server.Handler(grpcServer, rest.NewApi(signer, tables).Handler()))
+	t.Cleanup(ts.Close)
+
+	conn, err := grpc.NewClient(
+		strings.TrimPrefix(ts.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return ts, pb.NewBlackjackClient(conn)
+}
+
+func TestTableCreatedOverRestIsPlayableOverGrpc(t *testing.T) {
+	// Arrange
+	ts, client := setup(t)
+	resp, err := http.Post(ts.URL+"/v1/tables", "application/json", strings.NewReader(`{"playerName": "Player 1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var created rest.CreateGameResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err = client.AddPlayer(
+		context.Background(),
+		&pb.AddPlayerRequest{TableId: created.TableId, PlayerName: "Player 1"},
+	)
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableCreatedOverGrpcIsPlayableOverRest(t *testing.T) {
+	// Arrange
+	ts, client := setup(t)
+	created, err := client.CreateGame(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	resp, err := http.Post(
+		ts.URL+"/v1/tables/"+created.TableId+"/players",
+		"application/json",
+		strings.NewReader(`{"playerName": "Player 1"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Assert
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200; got %v", resp.StatusCode)
+	}
+	state, err := client.GetGameState(context.Background(), &pb.GetGameStateRequest{TableId: created.TableId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Players) != 1 {
+		t.Errorf("Expected 1 player; got %v", len(state.Players))
+	}
+}
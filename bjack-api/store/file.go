@@ -0,0 +1,242 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	journalFile  = "journal.jsonl"
+	// Number of journal entries after which the journal is folded into a new snapshot.
+	maxJournalEntries = 1000
+	dirPerm           = 0o750
+	filePerm          = 0o600
+)
+
+type journalOp string
+
+const (
+	opSave   journalOp = "save"
+	opDelete journalOp = "delete"
+)
+
+type journalEntry struct {
+	Op      journalOp       `json:"op"`
+	TableId string          `json:"tableId"`
+	Table   json.RawMessage `json:"table,omitempty"`
+}
+
+// FileStore keeps tables in a directory as a JSON snapshot
+// and an append-only journal of changes made since the snapshot was written.
+type FileStore struct {
+	mu             sync.Mutex
+	dir            string
+	records        map[string]json.RawMessage
+	journal        *os.File
+	journalEntries int
+}
+
+// NewFileStore opens the store in dir, creating the directory if needed.
+// Existing journal entries are folded into a fresh snapshot.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	s := &FileStore{
+		dir:     dir,
+		records: map[string]json.RawMessage{},
+	}
+	if err := s.readSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := s.replayJournal(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Save(tableId string, game *blackjack.Blackjack, version uint64) error {
+	data, err := encode(game, version)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(journalEntry{Op: opSave, TableId: tableId, Table: data}); err != nil {
+		return err
+	}
+	s.records[tableId] = data
+	return s.compactIfNeeded()
+}
+
+func (s *FileStore) Delete(tableId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[tableId]; !ok {
+		return nil
+	}
+	if err := s.append(journalEntry{Op: opDelete, TableId: tableId}); err != nil {
+		return err
+	}
+	delete(s.records, tableId)
+	return s.compactIfNeeded()
+}
+
+func (s *FileStore) LoadAll() (map[string]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return decodeAll(s.records)
+}
+
+// Ping checks that the directory is still there and the journal is open.
+func (s *FileStore) Ping() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(s.dir); err != nil {
+		return fmt.Errorf("store directory unavailable: %w", err)
+	}
+	if _, err := s.journal.Stat(); err != nil {
+		return fmt.Errorf("journal unavailable: %w", err)
+	}
+	return nil
+}
+
+// Close writes a snapshot of all records, so that the next start does not replay the journal.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.compact(); err != nil {
+		return err
+	}
+	if err := s.journal.Close(); err != nil {
+		return fmt.Errorf("failed to close journal: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) readSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) replayJournal() error {
+	f, err := os.Open(filepath.Join(s.dir, journalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20) //nolint: mnd
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Most likely a write torn by a crash, nothing after it can be trusted.
+			slog.Warn("Ignoring the rest of a corrupted journal", "error", err)
+			break
+		}
+		switch entry.Op {
+		case opSave:
+			s.records[entry.TableId] = entry.Table
+		case opDelete:
+			delete(s.records, entry.TableId)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) append(entry journalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+	if _, err := s.journal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := s.journal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	s.journalEntries++
+	return nil
+}
+
+func (s *FileStore) compactIfNeeded() error {
+	if s.journalEntries < maxJournalEntries {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact writes all records to a new snapshot and starts an empty journal.
+func (s *FileStore) compact() error {
+	data, err := json.Marshal(s.records)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	tmpPath := filepath.Join(s.dir, snapshotFile+".tmp")
+	if err := writeFileSync(tmpPath, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.dir, snapshotFile)); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	if s.journal != nil {
+		if err := s.journal.Close(); err != nil {
+			return fmt.Errorf("failed to close journal: %w", err)
+		}
+	}
+	journal, err := os.OpenFile(
+		filepath.Join(s.dir, journalFile),
+		os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND,
+		filePerm,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	s.journal = journal
+	s.journalEntries = 0
+	return nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, filePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create %v: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write %v: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %v: %w", path, err)
+	}
+	return nil
+}
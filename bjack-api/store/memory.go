@@ -0,0 +1,48 @@
+package store
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+)
+
+// MemoryStore keeps encoded tables in memory. Meant for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]json.RawMessage
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: map[string]json.RawMessage{},
+	}
+}
+
+func (s *MemoryStore) Save(tableId string, game *blackjack.Blackjack, version uint64) error {
+	data, err := encode(game, version)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[tableId] = data
+	return nil
+}
+
+func (s *MemoryStore) Delete(tableId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, tableId)
+	return nil
+}
+
+func (s *MemoryStore) LoadAll() (map[string]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return decodeAll(s.records)
+}
+
+func (s *MemoryStore) Ping() error {
+	return nil
+}
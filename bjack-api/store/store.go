@@ -0,0 +1,111 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/deck"
+)
+
+// Store persists games so that tables survive a server restart.
+type Store interface {
+	Save(tableId string, game *blackjack.Blackjack, version uint64) error
+	Delete(tableId string) error
+	LoadAll() (map[string]Record, error)
+	// Ping reports whether the store can be used, for readiness checks.
+	Ping() error
+}
+
+// Record is a saved game together with the version of its table.
+type Record struct {
+	Game    *blackjack.Blackjack
+	Version uint64
+}
+
+// tableRecord holds the complete state of a game,
+// including the fields hidden from API clients.
+type tableRecord struct {
+	Deck          []deck.Card     `json:"deck"`
+	Hands         [][]deck.Card   `json:"hands"`
+	Players       []playerRecord  `json:"players"`
+	State         blackjack.State `json:"state"`
+	CurrentPlayer int             `json:"currentPlayer"`
+	Version       uint64          `json:"version"`
+	Rules         blackjack.Rules `json:"rules"`
+}
+
+type playerRecord struct {
+	Id      string            `json:"id"`
+	Name    string            `json:"name"`
+	IsReady bool              `json:"isReady"`
+	Chips   int               `json:"chips"`
+	Bet     int               `json:"bet"`
+	Outcome blackjack.Outcome `json:"outcome"`
+}
+
+func encode(game *blackjack.Blackjack, version uint64) (json.RawMessage, error) {
+	record := tableRecord{
+		Deck:          game.Deck,
+		Hands:         game.Hands,
+		Players:       make([]playerRecord, 0, len(game.Players)),
+		State:         game.State,
+		CurrentPlayer: game.CurrentPlayer,
+		Version:       version,
+		Rules:         game.Rules,
+	}
+	for _, p := range game.Players {
+		record.Players = append(record.Players, playerRecord{
+			Id:      p.Id,
+			Name:    p.Name,
+			IsReady: p.IsReady,
+			Chips:   p.Chips,
+			Bet:     p.Bet,
+			Outcome: p.Outcome,
+		})
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode table: %w", err)
+	}
+	return data, nil
+}
+
+func decode(data json.RawMessage) (Record, error) {
+	var record tableRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return Record{}, fmt.Errorf("failed to decode table: %w", err)
+	}
+	game := blackjack.New(nil)
+	game.Deck = record.Deck
+	game.Hands = record.Hands
+	game.State = record.State
+	game.CurrentPlayer = record.CurrentPlayer
+	// Tables saved before rules were configurable have none.
+	if record.Rules != (blackjack.Rules{}) {
+		game.Rules = record.Rules
+	}
+	for _, p := range record.Players {
+		game.Players = append(game.Players, &blackjack.Player{
+			Id:      p.Id,
+			Name:    p.Name,
+			IsReady: p.IsReady,
+			Chips:   p.Chips,
+			Bet:     p.Bet,
+			Outcome: p.Outcome,
+		})
+	}
+	return Record{Game: &game, Version: record.Version}, nil
+}
+
+func decodeAll(records map[string]json.RawMessage) (map[string]Record, error) {
+	decoded := make(map[string]Record, len(records))
+	for tableId, data := range records {
+		record, err := decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("table %v: %w", tableId, err)
+		}
+		decoded[tableId] = record
+	}
+	return decoded, nil
+}
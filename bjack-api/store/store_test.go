@@ -0,0 +1,237 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GRO4T/bjack-api/blackjack"
+	"github.com/GRO4T/bjack-api/store"
+)
+
+const (
+	testTableId = "ABC234"
+)
+
+func newGameInProgress(t *testing.T) *blackjack.Blackjack {
+	t.Helper()
+	game := blackjack.New(nil)
+	game.Rules = blackjack.Rules{MaxPlayers: 5, InitialChips: 500}
+	player, err := game.AddPlayer("Player 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.PlaceBet(player.Id, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := game.TogglePlayerReady(player.Id); err != nil {
+		t.Fatal(err)
+	}
+	return &game
+}
+
+func assertSameGame(t *testing.T, expected *blackjack.Blackjack, actual *blackjack.Blackjack) {
+	t.Helper()
+	if len(actual.Deck) != len(expected.Deck) || actual.Deck[0] != expected.Deck[0] {
+		t.Errorf("Expected deck to be restored")
+	}
+	if len(actual.GetPlayerHand(0)) != len(expected.GetPlayerHand(0)) {
+		t.Errorf("Expected %v cards; got %v", len(expected.GetPlayerHand(0)), len(actual.GetPlayerHand(0)))
+	}
+	if *actual.Players[0] != *expected.Players[0] {
+		t.Errorf("Expected player %+v; got %+v", *expected.Players[0], *actual.Players[0])
+	}
+	if actual.State != expected.State || actual.CurrentPlayer != expected.CurrentPlayer {
+		t.Errorf("Expected state %v and current player %v; got %v and %v",
+			expected.State, expected.CurrentPlayer, actual.State, actual.CurrentPlayer)
+	}
+	if actual.Rules != expected.Rules {
+		t.Errorf("Expected rules %+v; got %+v", expected.Rules, actual.Rules)
+	}
+}
+
+func TestMemoryStoreSaveAndLoad(t *testing.T) {
+	// Arrange
+	s := store.NewMemoryStore()
+	game := newGameInProgress(t)
+
+	// Act
+	if err := s.Save(testTableId, game, 7); err != nil {
+		t.Fatal(err)
+	}
+	games, err := s.LoadAll()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("Expected 1 table; got %v", len(games))
+	}
+	assertSameGame(t, game, games[testTableId].Game)
+	if games[testTableId].Version != 7 {
+		t.Errorf("Expected version 7; got %v", games[testTableId].Version)
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	// Arrange
+	s := store.NewMemoryStore()
+	if err := s.Save(testTableId, newGameInProgress(t), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	if err := s.Delete(testTableId); err != nil {
+		t.Fatal(err)
+	}
+	games, err := s.LoadAll()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 0 {
+		t.Errorf("Expected 0 tables; got %v", len(games))
+	}
+}
+
+func TestFileStoreRestoresFromJournal(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	s, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := newGameInProgress(t)
+	if err := s.Save(testTableId, game, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save("XYZ789", newGameInProgress(t), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("XYZ789"); err != nil {
+		t.Fatal(err)
+	}
+	// Not closed, as closing writes a snapshot.
+	t.Cleanup(func() { s.Close() })
+
+	// Act
+	reopened, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+	games, err := reopened.LoadAll()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Fatalf("Expected 1 table; got %v", len(games))
+	}
+	assertSameGame(t, game, games[testTableId].Game)
+	if games[testTableId].Version != 3 {
+		t.Errorf("Expected version 3; got %v", games[testTableId].Version)
+	}
+}
+
+func TestFileStoreCloseWritesSnapshot(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	s, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := newGameInProgress(t)
+	if err := s.Save(testTableId, game, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err = s.Close()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal, err := os.Stat(filepath.Join(dir, "journal.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.Size() != 0 {
+		t.Errorf("Expected an empty journal; got %v bytes", journal.Size())
+	}
+	reopened, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+	games, err := reopened.LoadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameGame(t, game, games[testTableId].Game)
+}
+
+func TestFileStorePing(t *testing.T) {
+	// Arrange
+	s, err := store.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := s.Ping()
+
+	// Act
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	if open != nil {
+		t.Errorf("Expected an open store to be usable; got %v", open)
+	}
+	if s.Ping() == nil {
+		t.Error("Expected a closed store to be unusable")
+	}
+}
+
+func TestFileStoreIgnoresTornJournalEntry(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	s, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(testTableId, newGameInProgress(t), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	journal, err := os.OpenFile(filepath.Join(dir, "journal.jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := journal.WriteString(`{"op":"save","tableId":"XYZ7`); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+
+	// Act
+	reopened, err := store.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reopened.Close() })
+	games, err := reopened.LoadAll()
+
+	// Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Errorf("Expected 1 table; got %v", len(games))
+	}
+}
@@ -31,6 +31,7 @@ export default function App() {
   const [gameId, setGameId] = useSessionStorage("gameId", "");
   const [playerName, setPlayerName] = useSessionStorage("playerName", "");
   const [playerId, setPlayerId] = useSessionStorage("playerId", "");
+  const [playerToken, setPlayerToken] = useSessionStorage("playerToken", "");
   const [gameStateSeq, setGameStateSeq] = useSessionStorage("gameStateSeq", 0);
   const [gameState, setGameState] = useSessionStorage(
     "gameState",
@@ -39,7 +40,7 @@ export default function App() {
   const webSocket = useRef<WebSocket | null>(null);
 
   useEffect(() => {
-    fetch(API_URL + "/tables/" + gameId)
+    fetch(API_URL + "/v1/tables/" + gameId)
       .then((res) => res.json())
       .then((body) => {
         setGameState(body);
@@ -51,14 +52,15 @@ export default function App() {
       return;
     }
     webSocket.current = new WebSocket(
-      import.meta.env.VITE_API_WS_URL + "/state-updates/" + gameId,
+      import.meta.env.VITE_API_WS_URL + "/v1/tables/" + gameId + "/updates",
     );
   }, [gameId]);
 
   if (webSocket.current) {
     webSocket.current.onmessage = (event) => {
-      if (event.data === "NewState") {
-        setGameStateSeq(gameStateSeq + 1);
+      const message = JSON.parse(event.data);
+      if (message.state) {
+        setGameState(message.state);
       }
     };
   }
@@ -70,6 +72,7 @@ export default function App() {
           onGameStartedChanged={setGameStarted}
           gameId={gameId}
           playerId={playerId}
+          playerToken={playerToken}
           gameState={gameState}
           gameStateSeq={gameStateSeq}
           onGameStateSeqChanged={setGameStateSeq}
@@ -80,6 +83,7 @@ export default function App() {
       <Game
         gameId={gameId}
         playerId={playerId}
+        playerToken={playerToken}
         gameState={gameState}
         playerName={playerName}
         onGameStartedChanged={setGameStarted}
@@ -94,6 +98,7 @@ export default function App() {
       onPlayerNameChange={setPlayerName}
       onGameIdChange={setGameId}
       onPlayerIdChange={setPlayerId}
+      onPlayerTokenChange={setPlayerToken}
       onGameStateChange={setGameState}
     />
   );
@@ -17,6 +17,7 @@ import spades from "../assets/spades.png";
 interface Props {
   gameId: string;
   playerId: string;
+  playerToken: string;
   gameState: GameState;
   playerName: string;
   onGameStartedChanged: Dispatch<SetStateAction<boolean>>;
@@ -25,17 +26,21 @@ interface Props {
 export default function Game({
   gameId,
   playerId,
+  playerToken,
   gameState,
   playerName,
   onGameStartedChanged,
 }: Props) {
   const PlayerAction = async (action: string) => {
     return await fetch(
-      API_URL + "/tables/" + gameId + "/" + playerId + "?action=" + action,
+      API_URL + "/v1/tables/" + gameId + "/players/" + playerId + "/actions",
       {
         method: "POST",
-        headers: { "Content-Type": "application/json" },
-        body: null,
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: "Bearer " + playerToken,
+        },
+        body: JSON.stringify({ action: action }),
       },
     );
   };
@@ -57,8 +62,9 @@ export default function Game({
   };
 
   const Leave = async () => {
-    await fetch(API_URL + "/tables/players/" + gameId + "/" + playerId, {
+    await fetch(API_URL + "/v1/tables/" + gameId + "/players/" + playerId, {
       method: "DELETE",
+      headers: { Authorization: "Bearer " + playerToken },
     });
     onGameStartedChanged(false);
   };
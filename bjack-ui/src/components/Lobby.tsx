@@ -1,4 +1,4 @@
-import { Dispatch, SetStateAction, useEffect } from "react";
+import { Dispatch, SetStateAction, useEffect, useState } from "react";
 import { API_URL } from "../constants";
 import { GameState, Player } from "../App";
 
@@ -6,6 +6,7 @@ interface Props {
   onGameStartedChanged: Dispatch<SetStateAction<boolean>>;
   gameId: string;
   playerId: string;
+  playerToken: string;
   gameState: GameState;
   gameStateSeq: number;
   onGameStateSeqChanged: Dispatch<SetStateAction<number>>;
@@ -15,6 +16,7 @@ export default function Lobby({
   onGameStartedChanged,
   gameId,
   playerId,
+  playerToken,
   gameState,
   gameStateSeq,
   onGameStateSeqChanged,
@@ -23,17 +25,31 @@ export default function Lobby({
     onGameStateSeqChanged(gameStateSeq + 1);
   }, []); // eslint-disable-line
 
+  const [isReady, setIsReady] = useState(false);
+
   const ReportReadiness = async () => {
-    return await fetch(API_URL + "/tables/ready/" + gameId + "/" + playerId, {
-      method: "POST",
-      headers: { "Content-Type": "application/json" },
-      body: null,
-    });
+    const resp = await fetch(
+      API_URL + "/v1/tables/" + gameId + "/players/" + playerId + "/ready",
+      {
+        method: "PUT",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: "Bearer " + playerToken,
+        },
+        body: JSON.stringify({ ready: !isReady }),
+      },
+    );
+    if (resp.ok) {
+      const player: Player = await resp.json();
+      setIsReady(player.isReady);
+    }
+    return resp;
   };
 
   const Leave = async () => {
-    await fetch(API_URL + "/tables/players/" + gameId + "/" + playerId, {
+    await fetch(API_URL + "/v1/tables/" + gameId + "/players/" + playerId, {
       method: "DELETE",
+      headers: { Authorization: "Bearer " + playerToken },
     });
     onGameStartedChanged(false);
   };
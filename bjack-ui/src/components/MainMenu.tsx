@@ -9,6 +9,7 @@ interface Props {
   onPlayerNameChange: Dispatch<SetStateAction<string>>;
   onGameStartedChange: Dispatch<SetStateAction<boolean>>;
   onPlayerIdChange: Dispatch<SetStateAction<string>>;
+  onPlayerTokenChange: Dispatch<SetStateAction<string>>;
   onGameStateChange: Dispatch<SetStateAction<GameState>>;
 }
 
@@ -19,12 +20,13 @@ export default function MainMenu({
   onPlayerNameChange,
   onGameStartedChange,
   onPlayerIdChange,
+  onPlayerTokenChange,
   onGameStateChange,
 }: Props) {
   const [info, setInfo] = useState("");
 
   const CallCreateGame = async (playerName: string) => {
-    return await fetch(API_URL + "/tables", {
+    return await fetch(API_URL + "/v1/tables", {
       method: "POST",
       headers: { "Content-Type": "application/json" },
       body: JSON.stringify({ playerName: playerName }),
@@ -32,7 +34,7 @@ export default function MainMenu({
   };
 
   const CallAddPlayer = async (tableId: string, playerName: string) => {
-    return await fetch(API_URL + "/tables/players/" + tableId, {
+    return await fetch(API_URL + "/v1/tables/" + tableId + "/players", {
       method: "POST",
       headers: { "Content-Type": "application/json" },
       body: JSON.stringify({ playerName: playerName }),
@@ -44,10 +46,10 @@ export default function MainMenu({
       const createGameResp = await CallCreateGame(playerName);
       if (!createGameResp.ok) {
         if (createGameResp.status == 400) {
-          const errMsg = await createGameResp.text();
-          setInfo(errMsg);
+          const problem = await createGameResp.json();
+          setInfo(problem["detail"]);
         }
-        throw new Error("POST /tables returned " + createGameResp.status);
+        throw new Error("POST /v1/tables returned " + createGameResp.status);
       }
       const createGameBody = await createGameResp.json();
       const addPlayerResp = await CallAddPlayer(
@@ -58,6 +60,7 @@ export default function MainMenu({
       onGameStartedChange(true);
       onGameIdChange(createGameBody["tableId"]);
       onPlayerIdChange(addPlayerBody["playerId"]);
+      onPlayerTokenChange(addPlayerBody["token"]);
       onGameStateChange(INITIAL_GAME_STATE);
     } catch (error) {
       if (error instanceof Error) {
@@ -72,17 +75,18 @@ export default function MainMenu({
     try {
       const addPlayerResp = await CallAddPlayer(gameId, playerName);
       if (!addPlayerResp.ok) {
-        if (addPlayerResp.status == 404 || addPlayerResp.status == 400) {
-          const errMsg = await addPlayerResp.text();
-          setInfo(errMsg);
+        if ([400, 404, 409].includes(addPlayerResp.status)) {
+          const problem = await addPlayerResp.json();
+          setInfo(problem["detail"]);
         }
         throw new Error(
-          "POST /tables/players/{tableId} returned " + addPlayerResp.status,
+          "POST /v1/tables/{tableId}/players returned " + addPlayerResp.status,
         );
       }
       const addPlayerBody = await addPlayerResp.json();
       onGameStartedChange(true);
       onPlayerIdChange(addPlayerBody["playerId"]);
+      onPlayerTokenChange(addPlayerBody["token"]);
       onGameStateChange(INITIAL_GAME_STATE);
     } catch (error) {
       if (error instanceof Error) {
@@ -104,7 +108,7 @@ export default function MainMenu({
         <input
           value={gameId}
           placeholder="tableId"
-          onChange={(e) => onGameIdChange(e.target.value)}
+          onChange={(e) => onGameIdChange(e.target.value.toUpperCase())}
         />
         <button onClick={StartGame}>Host a new game</button>
         <button onClick={() => JoinGame(gameId)}>Join a game</button>